@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -14,10 +17,18 @@ var (
 	ServerPort string
 	// DatabasePath 数据库路径
 	DatabasePath string
-	// JWTSecret JWT密钥
+	// DataDir 数据目录
+	DataDir string
+	// JWTSecret 服务端密钥，用于登录状态签名等HMAC场景
 	JWTSecret string
+	// JWTAlgorithm JWT签名算法：RS256 或 EdDSA
+	JWTAlgorithm string
+	// JWTKeyRotation JWT签名密钥轮换周期
+	JWTKeyRotation time.Duration
 	// JWTExpire JWT过期时间
 	JWTExpire time.Duration
+	// RefreshTokenExpire 刷新令牌过期时间
+	RefreshTokenExpire time.Duration
 	// SystemURL 系统URL
 	SystemURL string
 	// CZLClientID CZL Connect 客户端ID
@@ -38,8 +49,43 @@ var (
 	AIAPIKey string
 	// AIModel AI模型名称
 	AIModel string
+	// AIConcurrency 批量调用AI时的最大并发数
+	AIConcurrency int
+	// AIRequestsPerMinute 每分钟最多发送的AI请求数，0表示不限制
+	AIRequestsPerMinute int
+	// AITokensPerMinute 每分钟最多消耗的AI token数，0表示不限制
+	AITokensPerMinute int
+	// AICacheTTL AI结果缓存的有效期
+	AICacheTTL time.Duration
+	// AICacheMaxEntries AI结果缓存的最大条数，0表示不缓存
+	AICacheMaxEntries int
+	// AuthCookieMode 是否通过HttpOnly Cookie下发登录令牌
+	AuthCookieMode bool
+	// CookieDomain Cookie作用域名，为空时仅当前域名
+	CookieDomain string
+	// CookieSecure Cookie是否仅通过HTTPS发送
+	CookieSecure bool
+	// CookieSameSite Cookie的SameSite策略：lax, strict, none
+	CookieSameSite string
+	// AdminEmails 登录后自动成为管理员的邮箱列表
+	AdminEmails []string
+	// FirstUserAdmin 第一个注册的用户是否自动成为管理员
+	FirstUserAdmin bool
+	// BaseCurrency 折算月付价格使用的基准货币
+	BaseCurrency string
+	// PriceDropPercent 月付价格下降超过该百分比时记录降价事件
+	PriceDropPercent float64
+	// DealCheckInterval 同一优惠两次检查购买链接的最小间隔
+	DealCheckInterval time.Duration
+	// DealStockMarkers 购买页面中表示缺货的文字
+	DealStockMarkers []string
+	// DealOrderMarkers 购买页面中购买按钮的文字，用于区分页面中的多个套餐
+	DealOrderMarkers []string
 )
 
+// defaultJWTSecret 内置的默认密钥，仅允许在开发环境使用
+const defaultJWTSecret = "vps_monitor_secure_jwt_secret_key_2024"
+
 // LoadConfig 加载配置
 func LoadConfig() error {
 	// 加载.env文件
@@ -51,10 +97,21 @@ func LoadConfig() error {
 	ServerPort = getEnv("SERVER_PORT", "8080")
 	// 数据库路径
 	DatabasePath = getEnv("DATABASE_PATH", "data/database.db")
-	// JWT密钥 - 使用固定值避免重启后失效
-	JWTSecret = getEnv("JWT_SECRET", "vps_monitor_secure_jwt_secret_key_2024")
-	// JWT过期时间
-	JWTExpire = 30 * 24 * time.Hour // 固定30天
+	// 数据目录
+	DataDir = getEnv("DATA_DIR", "data")
+	// 服务端密钥 - 使用固定值避免重启后失效，生产环境必须显式配置
+	JWTSecret = getEnv("JWT_SECRET", defaultJWTSecret)
+	if IsProduction() && JWTSecret == defaultJWTSecret {
+		return fmt.Errorf("生产环境必须通过 JWT_SECRET 设置密钥，不能使用内置默认值")
+	}
+	// JWT签名算法，签名密钥保存在数据目录的keys子目录中
+	JWTAlgorithm = getEnv("JWT_ALGORITHM", "RS256")
+	// JWT签名密钥轮换周期
+	JWTKeyRotation = getEnvDuration("JWT_KEY_ROTATION", 30*24*time.Hour)
+	// JWT过期时间，访问令牌保持较短有效期，通过刷新令牌续期
+	JWTExpire = getEnvDuration("JWT_EXPIRE", 15*time.Minute)
+	// 刷新令牌过期时间
+	RefreshTokenExpire = getEnvDuration("REFRESH_TOKEN_EXPIRE", 30*24*time.Hour)
 
 	// 部署的域名
 	SystemURL = getEnv("SYSTEM_URL", "http://localhost:3000")
@@ -71,10 +128,53 @@ func LoadConfig() error {
 	AIURL = getEnv("AI_URL", "https://api.openai.com/v1")
 	AIAPIKey = getEnv("AI_API_KEY", "")
 	AIModel = getEnv("AI_MODEL", "gpt-3.5-turbo")
+	AIConcurrency = getEnvInt("AI_CONCURRENCY", 4)
+	if AIConcurrency < 1 {
+		AIConcurrency = 1
+	}
+	AIRequestsPerMinute = getEnvInt("AI_RPM", 0)
+	AITokensPerMinute = getEnvInt("AI_TPM", 0)
+	AICacheTTL = getEnvDuration("AI_CACHE_TTL", 30*24*time.Hour)
+	AICacheMaxEntries = getEnvInt("AI_CACHE_MAX_ENTRIES", 10000)
+
+	// Cookie模式配置，默认仍通过回调地址的查询参数下发令牌
+	AuthCookieMode = getEnv("AUTH_COOKIE_MODE", "false") == "true"
+	CookieDomain = getEnv("COOKIE_DOMAIN", "")
+	CookieSecure = getEnv("COOKIE_SECURE", fmt.Sprint(strings.HasPrefix(SystemURL, "https://"))) == "true"
+	CookieSameSite = strings.ToLower(getEnv("COOKIE_SAMESITE", "lax"))
+
+	// 管理员配置
+	AdminEmails = getEnvList("ADMIN_EMAILS")
+	FirstUserAdmin = getEnv("FIRST_USER_ADMIN", "false") == "true"
+
+	// 价格折算的基准货币
+	BaseCurrency = strings.ToUpper(getEnv("BASE_CURRENCY", "USD"))
+
+	// 降价检测阈值（百分比）
+	PriceDropPercent = getEnvFloat("PRICE_DROP_PERCENT", 10)
+
+	// 优惠状态检查配置
+	DealCheckInterval = getEnvDuration("DEAL_CHECK_INTERVAL", 6*time.Hour)
+	DealStockMarkers = getEnvList("DEAL_STOCK_MARKERS")
+	if len(DealStockMarkers) == 0 {
+		DealStockMarkers = []string{"out of stock", "sold out", "currently unavailable", "缺货", "售罄", "已售完", "库存不足"}
+	}
+	DealOrderMarkers = getEnvList("DEAL_ORDER_MARKERS")
+	if len(DealOrderMarkers) == 0 {
+		DealOrderMarkers = []string{"order", "buy", "add to cart", "purchase", "checkout", "购买", "订购", "下单", "加入购物车"}
+	}
+
+	// 登录提供商配置
+	loadOAuthProviders()
 
 	return nil
 }
 
+// IsProduction 是否运行在生产环境
+func IsProduction() bool {
+	return AppEnv == "production" || os.Getenv("GIN_MODE") == "release"
+}
+
 // getEnv 获取环境变量，如果不存在则返回默认值
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -83,3 +183,41 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvDuration 获取时长类型的环境变量，格式如 15m、720h，解析失败时返回默认值
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvFloat 获取数值类型的环境变量，解析失败或不为正数时返回默认值
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvInt 获取整数类型的环境变量，解析失败或为负数时返回默认值
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvList 获取逗号分隔的环境变量列表，忽略空项
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
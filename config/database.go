@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"go-nextjs/models"
+
 	"github.com/glebarez/sqlite" // 纯Go实现的SQLite驱动
 	"gorm.io/gorm"
 )
@@ -14,10 +16,7 @@ var DB *gorm.DB
 // InitDB 初始化数据库
 func InitDB() error {
 	// 获取数据库目录（优先使用环境变量中指定的数据目录）
-	dataDir := os.Getenv("DATA_DIR")
-	if dataDir == "" {
-		dataDir = "data"
-	}
+	dataDir := DataDir
 
 	log.Printf("数据目录: %s", dataDir)
 
@@ -67,6 +66,30 @@ func InitDB() error {
 		return err
 	}
 
+	// 自动迁移数据表
+	if err := migrate(); err != nil {
+		log.Printf("数据库迁移失败: %v", err)
+		return err
+	}
+
 	log.Println("数据库初始化成功")
 	return nil
 }
+
+// migrate 自动迁移数据表结构
+func migrate() error {
+	return DB.AutoMigrate(
+		&models.User{},
+		&models.OAuthState{},
+		&models.LoginCode{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.Deal{},
+		&models.Source{},
+		&models.DealPriceHistory{},
+		&models.DealEvent{},
+		&models.DealMergeLog{},
+		&models.ExchangeRate{},
+		&models.AICache{},
+	)
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"strings"
+)
+
+// OAuthProviderConfig 单个OAuth2/OIDC登录提供商的配置
+type OAuthProviderConfig struct {
+	Name         string   // 提供商名称，用于路由和记录用户来源
+	Type         string   // 提供商类型：czl_connect, github, google, oidc
+	ClientID     string   // 客户端ID
+	ClientSecret string   // 客户端密钥
+	Issuer       string   // OIDC issuer，配置后通过发现文档获取端点
+	AuthURL      string   // 授权URL
+	TokenURL     string   // 令牌URL
+	UserinfoURL  string   // 用户信息URL
+	RedirectURL  string   // 回调URL
+	Scopes       []string // 授权范围
+}
+
+// OAuthProviders 已配置的登录提供商
+var OAuthProviders []OAuthProviderConfig
+
+// loadOAuthProviders 加载登录提供商配置
+//
+// OAUTH_PROVIDERS 为逗号分隔的提供商名称，默认仅启用 czl_connect。
+// 每个提供商通过 OAUTH_<NAME>_* 环境变量配置，例如 OAUTH_GITHUB_CLIENT_ID。
+func loadOAuthProviders() {
+	names := getEnvList("OAUTH_PROVIDERS")
+	if len(names) == 0 {
+		names = []string{"czl_connect"}
+	}
+
+	OAuthProviders = nil
+	for _, name := range names {
+		name = strings.ToLower(name)
+		prefix := "OAUTH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		cfg := OAuthProviderConfig{
+			Name:         name,
+			Type:         getEnv(prefix+"TYPE", defaultProviderType(name)),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			AuthURL:      getEnv(prefix+"AUTH_URL", ""),
+			TokenURL:     getEnv(prefix+"TOKEN_URL", ""),
+			UserinfoURL:  getEnv(prefix+"USERINFO_URL", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", SystemURL+"/api/auth/"+name+"/callback"),
+			Scopes:       getEnvList(prefix + "SCOPES"),
+		}
+
+		// CZL Connect 兼容旧的配置项和回调地址
+		if cfg.Type == "czl_connect" {
+			cfg.ClientID = getEnv(prefix+"CLIENT_ID", CZLClientID)
+			cfg.ClientSecret = getEnv(prefix+"CLIENT_SECRET", CZLClientSecret)
+			cfg.AuthURL = getEnv(prefix+"AUTH_URL", CZLAuthURL)
+			cfg.TokenURL = getEnv(prefix+"TOKEN_URL", CZLTokenURL)
+			cfg.UserinfoURL = getEnv(prefix+"USERINFO_URL", CZLUserinfoURL)
+			cfg.RedirectURL = getEnv(prefix+"REDIRECT_URL", CZLRedirectURL)
+		}
+
+		OAuthProviders = append(OAuthProviders, cfg)
+	}
+}
+
+// defaultProviderType 根据名称推断提供商类型，未知名称按通用OIDC处理
+func defaultProviderType(name string) string {
+	switch name {
+	case "czl_connect", "github", "google":
+		return name
+	}
+	return "oidc"
+}
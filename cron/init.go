@@ -1,20 +1,31 @@
 package cron
 
 import (
+	"context"
+	"go-nextjs/pkg/jwk"
+	"go-nextjs/service"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
 )
 
 var c *cron.Cron
 
+// syncMu 防止上一轮同步未结束时重复执行
+var syncMu sync.Mutex
+
+// checkMu 防止上一轮优惠状态检查未结束时重复执行
+var checkMu sync.Mutex
+
 // Init 初始化定时任务
 func Init() error {
 	log.Println("开始进行初始化和定时任务")
 
 	c = cron.New(cron.WithSeconds())
 
-	// 每分钟检查一次需要同步的API
+	// 每分钟检查一次需要同步的数据来源
 	_, err := c.AddFunc("0 * * * * *", func() {
 		if err := checkAndSyncAPIs(); err != nil {
 			log.Printf("检查并同步API数据失败: %v", err)
@@ -23,14 +34,121 @@ func Init() error {
 	if err != nil {
 		return err
 	}
+
+	// 每小时清理过期的刷新令牌和会话
+	_, err = c.AddFunc("0 0 * * * *", func() {
+		if err := cleanupAuthData(); err != nil {
+			log.Printf("清理过期登录数据失败: %v", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// 每小时清理过期的AI缓存
+	_, err = c.AddFunc("0 45 * * * *", func() {
+		if err := cleanupAICache(); err != nil {
+			log.Printf("清理AI缓存失败: %v", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// 每小时归档到期的优惠并检查购买链接
+	_, err = c.AddFunc("0 15 * * * *", func() {
+		if err := checkDealStatus(); err != nil {
+			log.Printf("检查优惠状态失败: %v", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// 每小时检查是否需要轮换JWT签名密钥
+	_, err = c.AddFunc("0 30 * * * *", func() {
+		if _, err := jwk.Rotate(); err != nil {
+			log.Printf("轮换JWT签名密钥失败: %v", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
 	// 启动定时任务
 	c.Start()
 	log.Println("定时任务初始化成功")
 	return nil
 }
 
-// checkAndSyncAPIs 检查并同步需要更新的API数据
+// checkAndSyncAPIs 检查并同步已到期的数据来源
 func checkAndSyncAPIs() error {
+	if !syncMu.TryLock() {
+		log.Println("上一轮来源同步尚未结束，跳过本次检查")
+		return nil
+	}
+	defer syncMu.Unlock()
+
+	return service.SyncDueSources(context.Background())
+}
+
+// checkDealStatus 归档已过结束时间的优惠，并检查购买链接是否失效或售罄
+func checkDealStatus() error {
+	if !checkMu.TryLock() {
+		return nil
+	}
+	defer checkMu.Unlock()
+
+	archived, err := service.ArchiveEndedDeals()
+	if err != nil {
+		return err
+	}
+	if archived > 0 {
+		log.Printf("已归档 %d 个到期的优惠", archived)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	checked, changed, err := service.CheckDealAvailability(ctx)
+	if err != nil {
+		return err
+	}
+	if checked > 0 {
+		log.Printf("已检查 %d 个优惠的购买链接，%d 个状态变化", checked, changed)
+	}
+	return nil
+}
+
+// cleanupAuthData 清理过期的登录数据
+func cleanupAuthData() error {
+	count, err := service.CleanupRefreshTokens()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("已清理 %d 个过期的刷新令牌", count)
+	}
+
+	// 已失效的会话保留7天，便于查看登录记录
+	count, err = service.CleanupSessions(7 * 24 * time.Hour)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("已清理 %d 个失效的会话", count)
+	}
+	return nil
+}
+
+// cleanupAICache 清理过期和超出条数上限的AI缓存
+func cleanupAICache() error {
+	count, err := service.CleanupAICache()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("已清理 %d 条AI缓存", count)
+	}
 	return nil
 }
 
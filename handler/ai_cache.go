@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"go-nextjs/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAICacheStats 获取AI缓存的统计信息
+func GetAICacheStats(c *gin.Context) {
+	stats, err := service.GetAICacheStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取AI缓存统计失败"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// PurgeAICache 清空AI缓存，kind参数可只清空 vps 或 title 类型
+func PurgeAICache(c *gin.Context) {
+	deleted, err := service.PurgeAICache(c.Query("kind"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "清空AI缓存失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "清空成功", "deleted": deleted})
+}
@@ -1,32 +1,60 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"go-nextjs/config"
 	"go-nextjs/middleware"
+	"go-nextjs/models"
+	"go-nextjs/pkg/jwk"
+	"go-nextjs/pkg/oauth"
+	"go-nextjs/service"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// JWKS 发布用于验证访问令牌的公钥
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwk.JWKSMaxAge.Seconds())))
+	c.JSON(http.StatusOK, jwk.PublicJWKS())
+}
+
+// ListProviders 获取已启用的登录提供商
+func ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": oauth.Names()})
+}
+
 // Login 处理登录请求
 func Login(c *gin.Context) {
-	state := c.Query("state") // 获取state参数，用于保存回调后的目标URL
+	provider, ok := getProvider(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "不支持的登录方式"})
+		return
+	}
 
-	// 使用固定的后端回调URL
-	redirectURI := config.CZLRedirectURL
+	// 登录完成后返回的前端路径，兼容旧版本通过state传递目标URL
+	returnPath := c.Query("redirect")
+	if returnPath == "" {
+		returnPath = c.Query("state")
+	}
 
-	// 生成授权URL
-	var loginURL string
-	if state != "" {
-		loginURL = middleware.GetLoginURLWithState(redirectURI, state)
-	} else {
-		loginURL = middleware.GetLoginURL(redirectURI)
+	// 由服务端生成一次性state，绑定提供商、返回路径、PKCE校验码和nonce
+	state, err := service.CreateOAuthState(provider.Name(), returnPath)
+	if err != nil {
+		fmt.Printf("生成登录状态失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成登录状态失败"})
+		return
 	}
 
-	// 记录生成的URL，用于调试
-	fmt.Printf("生成的授权URL: %s\n", loginURL)
+	// 生成授权URL
+	loginURL := provider.AuthCodeURL(oauth.AuthParams{
+		State:        state.State,
+		CodeVerifier: state.CodeVerifier,
+		Nonce:        state.Nonce,
+	})
 
 	// 返回授权URL
 	c.JSON(http.StatusOK, gin.H{"url": loginURL})
@@ -34,9 +62,14 @@ func Login(c *gin.Context) {
 
 // Callback 处理OAuth2回调
 func Callback(c *gin.Context) {
-	// 记录回调参数
-	fullURL := c.Request.URL.String()
-	fmt.Printf("收到回调请求，完整URL: %s\n", fullURL)
+	// 只记录路径，查询参数中的授权码和state不能写入日志
+	fmt.Printf("收到回调请求: %s\n", c.Request.URL.Path)
+
+	provider, ok := getProvider(c)
+	if !ok {
+		redirectWithError(c, "不支持的登录方式", "")
+		return
+	}
 
 	code := c.Query("code")
 	if code == "" {
@@ -44,71 +77,241 @@ func Callback(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "未提供授权码"})
 		return
 	}
-	fmt.Printf("收到授权码，长度: %d\n", len(code))
 
-	// 获取state参数，这是用于防止CSRF攻击的随机值
-	state := c.Query("state")
-	fmt.Printf("回调state参数: %s\n", state)
+	// 校验并消费state，拒绝伪造、过期或重放的请求
+	state, err := service.ConsumeOAuthState(provider.Name(), c.Query("state"))
+	if err != nil {
+		fmt.Printf("回调state校验失败: %v\n", err)
+		redirectWithError(c, err.Error(), "")
+		return
+	}
+
+	params := oauth.AuthParams{
+		State:        state.State,
+		CodeVerifier: state.CodeVerifier,
+		Nonce:        state.Nonce,
+	}
+
+	// 前端回调页面URL
+	frontendRedirectURL := config.SystemURL + "/auth/callback"
+
+	var redirectURL string
+	if config.AuthCookieMode {
+		// 处理OAuth2回调，使用state中保存的PKCE校验码换取token
+		tokens, err := middleware.HandleCallback(c.Request.Context(), provider, code, params, sessionMeta(c))
+		if err != nil {
+			fmt.Printf("处理回调失败: %v\n", err)
+			// 重定向回前端，携带错误信息和原始返回路径
+			redirectWithError(c, "处理回调失败: "+err.Error(), state.ReturnPath)
+			return
+		}
+
+		// Cookie模式下令牌只写入HttpOnly Cookie，不出现在URL中
+		if err := middleware.SetAuthCookies(c, tokens); err != nil {
+			redirectWithError(c, "设置登录Cookie失败", state.ReturnPath)
+			return
+		}
+		redirectURL = frontendRedirectURL + "?redirect=" + url.QueryEscape(state.ReturnPath)
+	} else {
+		user, err := middleware.AuthenticateCallback(c.Request.Context(), provider, code, params)
+		if err != nil {
+			fmt.Printf("处理回调失败: %v\n", err)
+			redirectWithError(c, "处理回调失败: "+err.Error(), state.ReturnPath)
+			return
+		}
+
+		// URL中只携带短时有效的一次性登录码，令牌由前端通过POST换取，避免出现在浏览器历史、日志和Referer中
+		loginCode, err := service.CreateLoginCode(user.ID)
+		if err != nil {
+			fmt.Printf("生成登录码失败: %v\n", err)
+			redirectWithError(c, "生成登录码失败", state.ReturnPath)
+			return
+		}
+		redirectURL = frontendRedirectURL + "?code=" + url.QueryEscape(loginCode) +
+			"&redirect=" + url.QueryEscape(state.ReturnPath)
+	}
+
+	fmt.Printf("最终重定向URL: %s\n", frontendRedirectURL)
+	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
+}
+
+// ExchangeRequest 登录码换取令牌请求
+type ExchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Exchange 使用回调中的一次性登录码换取访问令牌和刷新令牌
+func Exchange(c *gin.Context) {
+	var req ExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未提供登录码"})
+		return
+	}
+
+	user, err := service.ConsumeLoginCode(req.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrLoginCodeInvalid) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		fmt.Printf("换取令牌失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "换取令牌失败"})
+		return
+	}
+
+	tokens, err := middleware.IssueTokens(user, sessionMeta(c))
+	if err != nil {
+		fmt.Printf("生成token失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		return
+	}
+
+	// 响应包含令牌，禁止缓存
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RefreshRequest 刷新令牌请求
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
 
-	// 使用与授权请求相同的重定向URL
-	redirectURI := config.CZLRedirectURL
-	fmt.Printf("使用重定向URL: %s\n", redirectURI)
+// Refresh 使用刷新令牌换取新的访问令牌，刷新令牌同时轮换
+func Refresh(c *gin.Context) {
+	var req RefreshRequest
+	// Cookie模式下请求体可以为空
+	_ = c.ShouldBindJSON(&req)
 
-	// 处理OAuth2回调
-	token, err := middleware.HandleCallback(code, redirectURI)
+	fromCookie := false
+	if req.RefreshToken == "" && config.AuthCookieMode {
+		req.RefreshToken, _ = c.Cookie(middleware.RefreshTokenCookie)
+		fromCookie = true
+	}
+	if req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未提供刷新令牌"})
+		return
+	}
+	if fromCookie && !middleware.VerifyCSRF(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "CSRF校验失败"})
+		return
+	}
+
+	tokens, err := middleware.RefreshTokens(req.RefreshToken)
 	if err != nil {
-		fmt.Printf("处理回调失败: %v\n", err)
-		// 前端回调页面
-		frontendRedirectURL := config.SystemURL + "/auth/callback"
-		// 重定向回前端，携带错误信息和原始state
-		errorRedirectURL := frontendRedirectURL + "?error=" + url.QueryEscape("处理回调失败: "+err.Error())
-		if state != "" {
-			errorRedirectURL += "&state=" + url.QueryEscape(state)
+		switch {
+		case errors.Is(err, service.ErrRefreshTokenInvalid),
+			errors.Is(err, service.ErrRefreshTokenExpired),
+			errors.Is(err, service.ErrRefreshTokenReused),
+			errors.Is(err, service.ErrSessionRevoked):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			fmt.Printf("刷新令牌失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "刷新令牌失败"})
 		}
-		c.Redirect(http.StatusTemporaryRedirect, errorRedirectURL)
 		return
 	}
 
-	// 验证生成的token不为空
-	if token == "" {
-		fmt.Println("回调错误: 生成的token为空")
-		frontendRedirectURL := config.SystemURL + "/auth/callback"
-		errorRedirectURL := frontendRedirectURL + "?error=" + url.QueryEscape("生成的token为空")
-		if state != "" {
-			errorRedirectURL += "&state=" + url.QueryEscape(state)
+	if fromCookie {
+		if err := middleware.SetAuthCookies(c, tokens); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "设置登录Cookie失败"})
+			return
 		}
-		c.Redirect(http.StatusTemporaryRedirect, errorRedirectURL)
+		c.JSON(http.StatusOK, gin.H{"expires_in": tokens.ExpiresIn})
 		return
 	}
 
-	fmt.Printf("成功生成token，长度: %d\n", len(token))
+	c.JSON(http.StatusOK, tokens)
+}
 
-	// 前端回调页面URL
-	frontendRedirectURL := config.SystemURL + "/auth/callback"
-	// 重定向到前端，并携带token和state
-	redirectURL := frontendRedirectURL + "?token=" + url.QueryEscape(token)
-	if state != "" {
-		redirectURL += "&state=" + url.QueryEscape(state)
+// getProvider 根据路由参数获取登录提供商，旧版路由默认使用 CZL Connect
+func getProvider(c *gin.Context) (oauth.Provider, bool) {
+	name := c.Param("provider")
+	if name == "" {
+		name = models.ProviderCZLConnect
 	}
+	return oauth.Get(name)
+}
 
-	fmt.Printf("最终重定向URL: %s\n", redirectURL)
-	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
+// redirectWithError 携带错误信息重定向回前端回调页面
+func redirectWithError(c *gin.Context, message string, returnPath string) {
+	errorRedirectURL := config.SystemURL + "/auth/callback?error=" + url.QueryEscape(message)
+	if returnPath != "" {
+		errorRedirectURL += "&redirect=" + url.QueryEscape(returnPath)
+	}
+	c.Redirect(http.StatusTemporaryRedirect, errorRedirectURL)
 }
 
 // GetCurrentUser 获取当前登录用户信息
 func GetCurrentUser(c *gin.Context) {
-	// 从上下文中获取用户信息
-	user, exists := c.Get("user")
-	if !exists {
+	// 从上下文中获取用户ID
+	userID, ok := currentUserID(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"user": user})
+	// 从数据库读取最新的用户信息
+	user, err := service.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user.ToUserInfo()})
+}
+
+// currentUserID 从上下文中获取当前用户的本地ID
+func currentUserID(c *gin.Context) (uint, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+	str, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(str, 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
 }
 
-// Logout 处理登出请求
+// Logout 处理登出请求，吊销当前会话
 func Logout(c *gin.Context) {
-	// 由于使用的是JWT，服务端不需要特殊处理，只需前端清除token
+	if err := service.RevokeSession(c.GetString("session_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登出失败"})
+		return
+	}
+	if config.AuthCookieMode {
+		middleware.ClearAuthCookies(c)
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "登出成功"})
 }
+
+// LogoutAll 退出当前用户的所有会话
+func LogoutAll(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	count, err := service.RevokeUserSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登出失败"})
+		return
+	}
+	if config.AuthCookieMode {
+		middleware.ClearAuthCookies(c)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已退出所有设备", "revoked": count})
+}
+
+// sessionMeta 获取创建会话时记录的客户端信息
+func sessionMeta(c *gin.Context) service.SessionMeta {
+	return service.SessionMeta{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
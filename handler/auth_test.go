@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"go-nextjs/pkg/jwk"
+	"go-nextjs/pkg/oauth"
+	"go-nextjs/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeAuth 登录测试环境
+type fakeAuth struct {
+	router     *gin.Engine
+	exchanges  int32    // 授权服务器收到的换取令牌请求数
+	challenges sync.Map // 授权码对应的PKCE code_challenge
+}
+
+// setupAuthTest 准备内存数据库、签名密钥和一个指向假授权服务器的登录提供商
+func setupAuthTest(t *testing.T) *fakeAuth {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接独立
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.User{}, &models.OAuthState{}, &models.LoginCode{}, &models.RefreshToken{}, &models.Session{}); err != nil {
+		t.Fatal(err)
+	}
+	config.DB = db
+
+	config.DataDir = t.TempDir()
+	config.JWTSecret = "test-secret"
+	config.JWTAlgorithm = "EdDSA"
+	config.JWTKeyRotation = 24 * time.Hour
+	config.JWTExpire = 15 * time.Minute
+	config.RefreshTokenExpire = 24 * time.Hour
+	config.SystemURL = "http://app.test"
+	config.AuthCookieMode = true
+	if err := jwk.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 假授权服务器：校验PKCE后签发令牌，并返回固定的用户信息
+	f := &fakeAuth{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&f.exchanges, 1)
+		r.ParseForm()
+		challenge, ok := f.challenges.Load(r.Form.Get("code"))
+		if !ok || challenge != oauth.CodeChallengeS256(r.Form.Get("code_verifier")) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "at", "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"sub": "u1", "preferred_username": "alice", "email": "alice@example.com"})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	provider, err := oauth.New(config.OAuthProviderConfig{
+		Name:        "fake",
+		Type:        "oidc",
+		ClientID:    "client",
+		AuthURL:     server.URL + "/authorize",
+		TokenURL:    server.URL + "/token",
+		UserinfoURL: server.URL + "/userinfo",
+		RedirectURL: "http://app.test/api/auth/fake/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oauth.Register(provider)
+
+	f.router = gin.New()
+	f.router.GET("/api/auth/:provider/login", Login)
+	f.router.GET("/api/auth/:provider/callback", Callback)
+	f.router.POST("/api/auth/exchange", Exchange)
+	return f
+}
+
+// login 发起登录，模拟用户在授权服务器上同意授权并签发code，返回授权URL中的state
+func (f *fakeAuth) login(t *testing.T, code string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/auth/fake/login?redirect=/deals", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("login status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		URL string `json:"url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	u, err := url.Parse(resp.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.challenges.Store(code, u.Query().Get("code_challenge"))
+	return u.Query().Get("state")
+}
+
+// callback 访问回调地址并返回重定向目标
+func (f *fakeAuth) callback(t *testing.T, code string, state string) *url.URL {
+	t.Helper()
+	location, _ := f.callbackResponse(t, code, state)
+	return location
+}
+
+// callbackResponse 访问回调地址，返回重定向目标和响应
+func (f *fakeAuth) callbackResponse(t *testing.T, code string, state string) (*url.URL, *httptest.ResponseRecorder) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	target := "/api/auth/fake/callback?code=" + url.QueryEscape(code) + "&state=" + url.QueryEscape(state)
+	f.router.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("callback status = %d, body = %s", w.Code, w.Body.String())
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location, w
+}
+
+// exchange 使用一次性登录码换取令牌
+func (f *fakeAuth) exchange(t *testing.T, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(ExchangeRequest{Code: code})
+	req := httptest.NewRequest("POST", "/api/auth/exchange", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCallbackSuccess(t *testing.T) {
+	f := setupAuthTest(t)
+
+	state := f.login(t, "code-1")
+	location, w := f.callbackResponse(t, "code-1", state)
+
+	if msg := location.Query().Get("error"); msg != "" {
+		t.Fatalf("callback error = %q", msg)
+	}
+	if got := location.Query().Get("redirect"); got != "/deals" {
+		t.Errorf("redirect = %q, want /deals", got)
+	}
+	if location.Query().Get("refresh_token") != "" || location.Query().Get("token") != "" {
+		t.Errorf("tokens leaked into redirect URL: %s", location)
+	}
+	if n := atomic.LoadInt32(&f.exchanges); n != 1 {
+		t.Errorf("token exchanges = %d, want 1", n)
+	}
+
+	// Cookie模式下刷新令牌只通过HttpOnly Cookie下发
+	var refresh *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "refresh_token" {
+			refresh = cookie
+		}
+	}
+	if refresh == nil || refresh.Value == "" || !refresh.HttpOnly || refresh.Path != "/api/auth" {
+		t.Errorf("refresh cookie = %+v, want HttpOnly cookie scoped to /api/auth", refresh)
+	}
+}
+
+func TestCallbackLoginCodeExchange(t *testing.T) {
+	f := setupAuthTest(t)
+	config.AuthCookieMode = false
+
+	state := f.login(t, "code-1")
+	location, w := f.callbackResponse(t, "code-1", state)
+
+	// 非Cookie模式下回调URL只携带一次性登录码，不包含任何令牌
+	if msg := location.Query().Get("error"); msg != "" {
+		t.Fatalf("callback error = %q", msg)
+	}
+	for _, key := range []string{"token", "refresh_token", "expires_in"} {
+		if location.Query().Has(key) {
+			t.Errorf("redirect URL contains %s: %s", key, location)
+		}
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Errorf("cookies set in header mode: %v", w.Result().Cookies())
+	}
+	code := location.Query().Get("code")
+	if code == "" {
+		t.Fatalf("redirect URL has no login code: %s", location)
+	}
+
+	resp := f.exchange(t, code)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("exchange status = %d, body = %s", resp.Code, resp.Body.String())
+	}
+	if got := resp.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+	var tokens struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &tokens)
+	if tokens.Token == "" || tokens.RefreshToken == "" || tokens.ExpiresIn != int64(config.JWTExpire.Seconds()) {
+		t.Errorf("exchange response = %s", resp.Body.String())
+	}
+
+	// 登录码只能使用一次
+	if resp := f.exchange(t, code); resp.Code != http.StatusUnauthorized {
+		t.Errorf("second exchange status = %d, want 401", resp.Code)
+	}
+	if resp := f.exchange(t, "forged"); resp.Code != http.StatusUnauthorized {
+		t.Errorf("forged code exchange status = %d, want 401", resp.Code)
+	}
+	if resp := f.exchange(t, ""); resp.Code != http.StatusBadRequest {
+		t.Errorf("empty code exchange status = %d, want 400", resp.Code)
+	}
+}
+
+func TestExchangeExpiredLoginCode(t *testing.T) {
+	f := setupAuthTest(t)
+
+	user := &models.User{Username: "bob", Email: "bob@example.com"}
+	if err := config.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	code, err := service.CreateLoginCode(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.DB.Model(&models.LoginCode{}).Where("user_id = ?", user.ID).
+		Update("expires_at", time.Now().Add(-time.Second))
+
+	if resp := f.exchange(t, code); resp.Code != http.StatusUnauthorized {
+		t.Errorf("expired code exchange status = %d, want 401", resp.Code)
+	}
+}
+
+func TestCallbackReplayedState(t *testing.T) {
+	f := setupAuthTest(t)
+
+	state := f.login(t, "code-1")
+	if msg := f.callback(t, "code-1", state).Query().Get("error"); msg != "" {
+		t.Fatalf("first callback error = %q", msg)
+	}
+
+	// 同一个state再次回调必须被拒绝，且不会再请求授权服务器
+	location := f.callback(t, "code-1", state)
+	if msg := location.Query().Get("error"); msg != service.ErrStateReplayed.Error() {
+		t.Errorf("replay error = %q, want %q", msg, service.ErrStateReplayed.Error())
+	}
+	if n := atomic.LoadInt32(&f.exchanges); n != 1 {
+		t.Errorf("token exchanges = %d, want 1", n)
+	}
+}
+
+func TestCallbackExpiredState(t *testing.T) {
+	f := setupAuthTest(t)
+
+	state := f.login(t, "code-1")
+	config.DB.Model(&models.OAuthState{}).Where("state = ?", state).
+		Update("expires_at", time.Now().Add(-time.Minute))
+
+	location := f.callback(t, "code-1", state)
+	if msg := location.Query().Get("error"); msg != service.ErrStateExpired.Error() {
+		t.Errorf("expired error = %q, want %q", msg, service.ErrStateExpired.Error())
+	}
+	if n := atomic.LoadInt32(&f.exchanges); n != 0 {
+		t.Errorf("token exchanges = %d, want 0", n)
+	}
+}
+
+func TestCallbackInvalidState(t *testing.T) {
+	tests := []struct {
+		name  string
+		state func(valid string) string
+	}{
+		{"empty", func(string) string { return "" }},
+		{"unsigned", func(string) string { return "forged" }},
+		{"bad signature", func(valid string) string {
+			nonce, _, _ := strings.Cut(valid, ".")
+			return nonce + ".AAAA"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := setupAuthTest(t)
+			valid := f.login(t, "code-1")
+
+			location := f.callback(t, "code-1", tt.state(valid))
+			if msg := location.Query().Get("error"); msg != service.ErrStateInvalid.Error() {
+				t.Errorf("error = %q, want %q", msg, service.ErrStateInvalid.Error())
+			}
+			if n := atomic.LoadInt32(&f.exchanges); n != 0 {
+				t.Errorf("token exchanges = %d, want 0", n)
+			}
+		})
+	}
+}
+
+func TestCallbackStateFromOtherProvider(t *testing.T) {
+	f := setupAuthTest(t)
+
+	// 为其他提供商生成的state不能用于当前提供商的回调
+	other, err := service.CreateOAuthState("other", "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	location := f.callback(t, "code-1", other.State)
+	if msg := location.Query().Get("error"); msg != service.ErrStateInvalid.Error() {
+		t.Errorf("error = %q, want %q", msg, service.ErrStateInvalid.Error())
+	}
+}
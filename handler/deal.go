@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"errors"
+	"go-nextjs/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// dealListQuery 优惠列表的查询参数
+type dealListQuery struct {
+	Location string  `form:"location"`
+	Provider string  `form:"provider"`
+	MinCPU   int     `form:"min_cpu"`
+	MinRAM   float64 `form:"min_ram"`  // 单位GB
+	MinDisk  int     `form:"min_disk"` // 单位GB
+	MaxPrice float64 `form:"max_price"`
+	IPv6     bool    `form:"ipv6"`
+	// IncludeInactive 为true时包含售罄、失效和已归档的优惠
+	IncludeInactive bool   `form:"include_inactive"`
+	Sort            string `form:"sort"`
+	Cursor          string `form:"cursor"`
+	Limit           int    `form:"limit"`
+}
+
+// ListDeals 获取优惠列表，支持筛选、排序和游标分页
+func ListDeals(c *gin.Context) {
+	var params dealListQuery
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	page, err := service.ListDeals(&service.DealQuery{
+		Location:        params.Location,
+		Provider:        params.Provider,
+		MinCPU:          params.MinCPU,
+		MinRAMMB:        int(params.MinRAM * 1024),
+		MinDisk:         params.MinDisk,
+		MaxPrice:        params.MaxPrice,
+		IPv6:            params.IPv6,
+		IncludeInactive: params.IncludeInactive,
+		Sort:            params.Sort,
+		Cursor:          params.Cursor,
+		Limit:           params.Limit,
+	})
+	if err != nil {
+		respondDealError(c, err, "获取优惠列表失败")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetDeal 获取优惠详情
+func GetDeal(c *gin.Context) {
+	id, ok := dealID(c)
+	if !ok {
+		return
+	}
+
+	deal, err := service.GetDeal(id)
+	if err != nil {
+		respondDealError(c, err, "获取优惠失败")
+		return
+	}
+
+	// 返回主优惠在各来源中的链接，已合并的优惠返回其主优惠的链接
+	canonicalID := deal.ID
+	if deal.CanonicalID != nil {
+		canonicalID = *deal.CanonicalID
+	}
+	links, err := service.ListDealLinks(canonicalID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取优惠失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deal": deal, "links": links})
+}
+
+// ListDealPrices 获取优惠的价格历史
+func ListDealPrices(c *gin.Context) {
+	id, ok := dealID(c)
+	if !ok {
+		return
+	}
+
+	prices, err := service.ListDealPrices(id)
+	if err != nil {
+		respondDealError(c, err, "获取价格历史失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"prices": prices})
+}
+
+// ListDealEvents 按ID顺序拉取优惠事件，after为上次拉取到的最后一个事件ID
+func ListDealEvents(c *gin.Context) {
+	var params struct {
+		Type  string `form:"type"`
+		After uint   `form:"after"`
+		Limit int    `form:"limit"`
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	events, err := service.ListDealEvents(params.Type, params.After, params.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取优惠事件失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// CreateDeal 创建优惠
+func CreateDeal(c *gin.Context) {
+	var input service.DealInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	deal, err := service.CreateDeal(&input)
+	if err != nil {
+		respondDealError(c, err, "创建优惠失败")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"deal": deal})
+}
+
+// UpdateDeal 更新优惠
+func UpdateDeal(c *gin.Context) {
+	id, ok := dealID(c)
+	if !ok {
+		return
+	}
+
+	var input service.DealInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	deal, err := service.UpdateDeal(id, &input)
+	if err != nil {
+		respondDealError(c, err, "更新优惠失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deal": deal})
+}
+
+// DeleteDeal 删除优惠
+func DeleteDeal(c *gin.Context) {
+	id, ok := dealID(c)
+	if !ok {
+		return
+	}
+
+	if err := service.DeleteDeal(id); err != nil {
+		respondDealError(c, err, "删除优惠失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// dealID 解析路由中的优惠ID，失败时直接返回400
+func dealID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的优惠ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondDealError 根据错误类型返回对应的状态码
+func respondDealError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "优惠不存在"})
+	case errors.Is(err, service.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
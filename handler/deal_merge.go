@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"go-nextjs/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MergeRequest 合并或拆分优惠的请求
+type MergeRequest struct {
+	DealIDs []uint `json:"deal_ids" binding:"required"`
+	Reason  string `json:"reason"`
+}
+
+// MergeDeals 将其他优惠合并到指定的主优惠
+func MergeDeals(c *gin.Context) {
+	id, ok := dealID(c)
+	if !ok {
+		return
+	}
+
+	var req MergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	userID, _ := currentUserID(c)
+
+	if err := service.MergeDeals(id, req.DealIDs, userID, req.Reason); err != nil {
+		respondDealError(c, err, "合并优惠失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "合并成功"})
+}
+
+// SplitDeals 将已合并的优惠从主优惠中拆分出来
+func SplitDeals(c *gin.Context) {
+	id, ok := dealID(c)
+	if !ok {
+		return
+	}
+
+	var req MergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	userID, _ := currentUserID(c)
+
+	if err := service.SplitDeals(id, req.DealIDs, userID, req.Reason); err != nil {
+		respondDealError(c, err, "拆分优惠失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "拆分成功"})
+}
+
+// ListDealMergeLogs 获取主优惠的合并记录
+func ListDealMergeLogs(c *gin.Context) {
+	id, ok := dealID(c)
+	if !ok {
+		return
+	}
+
+	logs, err := service.ListDealMergeLogs(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取合并记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"logs": logs})
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-nextjs/config"
+	"go-nextjs/middleware"
+	"go-nextjs/models"
+	"go-nextjs/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dealTest 优惠接口测试环境
+type dealTest struct {
+	router     *gin.Engine
+	adminToken string
+	userToken  string
+}
+
+// setupDealTest 在登录测试环境的基础上迁移优惠相关的表，注册与正式路由相同的优惠接口
+func setupDealTest(t *testing.T) *dealTest {
+	t.Helper()
+	setupAuthTest(t)
+	err := config.DB.AutoMigrate(&models.Deal{}, &models.DealPriceHistory{}, &models.DealEvent{}, &models.DealMergeLog{}, &models.ExchangeRate{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.BaseCurrency = "USD"
+	config.AIAPIKey = ""
+
+	d := &dealTest{
+		adminToken: issueToken(t, "admin", models.RoleAdmin),
+		userToken:  issueToken(t, "bob", models.RoleUser),
+	}
+	d.router = gin.New()
+	public := d.router.Group("/api")
+	public.GET("/deals", ListDeals)
+	public.GET("/deals/:id", GetDeal)
+	admin := d.router.Group("/api")
+	admin.Use(middleware.AuthRequired(), middleware.AdminRequired())
+	admin.POST("/deals", CreateDeal)
+	admin.PUT("/deals/:id", UpdateDeal)
+	admin.DELETE("/deals/:id", DeleteDeal)
+	return d
+}
+
+// issueToken 创建用户并签发访问令牌
+func issueToken(t *testing.T, username, role string) string {
+	t.Helper()
+	user := &models.User{Provider: "fake", ExternID: username, Username: username, Email: username + "@example.com", Role: role}
+	if err := config.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := middleware.IssueTokens(user, service.SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens.AccessToken
+}
+
+// do 发送请求，token为空时不携带认证信息，out不为nil时解析响应
+func (d *dealTest) do(t *testing.T, method, target, token string, body interface{}, out interface{}) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	d.router.ServeHTTP(w, req)
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: invalid response %s", method, target, w.Body.String())
+		}
+	}
+	return w.Code
+}
+
+// createDeal 通过管理接口创建优惠
+func (d *dealTest) createDeal(t *testing.T, input service.DealInput) models.Deal {
+	t.Helper()
+	var resp struct {
+		Deal models.Deal `json:"deal"`
+	}
+	if code := d.do(t, "POST", "/api/deals", d.adminToken, input, &resp); code != http.StatusCreated {
+		t.Fatalf("create status = %d", code)
+	}
+	return resp.Deal
+}
+
+func TestListDealsPublic(t *testing.T) {
+	d := setupDealTest(t)
+	cheap := d.createDeal(t, service.DealInput{Title: "KVM 1G", Provider: "RackNerd", Price: 10, BillingCycle: models.BillingAnnually})
+	d.createDeal(t, service.DealInput{Title: "KVM 2G", Provider: "RackNerd", Price: 5})
+	d.createDeal(t, service.DealInput{Title: "KVM 4G", Provider: "BandwagonHost", Price: 1, Status: models.DealStatusExpired})
+
+	var page service.DealPage
+	if code := d.do(t, "GET", "/api/deals?sort=price&limit=1", "", nil, &page); code != http.StatusOK {
+		t.Fatalf("list status = %d", code)
+	}
+	// 默认不包含失效的优惠，按月付价格升序
+	if len(page.Deals) != 1 || page.Deals[0].ID != cheap.ID || page.NextCursor == "" || page.BaseCurrency != "USD" {
+		t.Fatalf("page = %+v", page)
+	}
+
+	if code := d.do(t, "GET", "/api/deals?sort=price&limit=1&cursor="+page.NextCursor, "", nil, &page); code != http.StatusOK {
+		t.Fatalf("next page status = %d", code)
+	}
+	if len(page.Deals) != 1 || page.Deals[0].Title != "KVM 2G" || page.NextCursor != "" {
+		t.Errorf("next page = %+v", page)
+	}
+
+	if d.do(t, "GET", "/api/deals?include_inactive=true&provider=BandwagonHost", "", nil, &page); len(page.Deals) != 1 {
+		t.Errorf("inactive deals = %d, want 1", len(page.Deals))
+	}
+	if code := d.do(t, "GET", "/api/deals?min_cpu=abc", "", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("invalid query status = %d, want 400", code)
+	}
+}
+
+func TestGetDeal(t *testing.T) {
+	d := setupDealTest(t)
+	deal := d.createDeal(t, service.DealInput{Title: "KVM", Price: 5, URL: "https://vendor.test/cart"})
+
+	var resp struct {
+		Deal  models.Deal        `json:"deal"`
+		Links []service.DealLink `json:"links"`
+	}
+	if code := d.do(t, "GET", fmt.Sprintf("/api/deals/%d", deal.ID), "", nil, &resp); code != http.StatusOK {
+		t.Fatalf("get status = %d", code)
+	}
+	if resp.Deal.ID != deal.ID || len(resp.Links) != 1 || resp.Links[0].URL != "https://vendor.test/cart" {
+		t.Errorf("response = %+v", resp)
+	}
+
+	if code := d.do(t, "GET", "/api/deals/999", "", nil, nil); code != http.StatusNotFound {
+		t.Errorf("missing deal status = %d, want 404", code)
+	}
+	if code := d.do(t, "GET", "/api/deals/abc", "", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("invalid id status = %d, want 400", code)
+	}
+}
+
+func TestDealAdminCRUD(t *testing.T) {
+	d := setupDealTest(t)
+	input := service.DealInput{Title: "KVM", Price: 5}
+
+	// 未登录和非管理员不能修改优惠
+	if code := d.do(t, "POST", "/api/deals", "", input, nil); code != http.StatusUnauthorized {
+		t.Errorf("anonymous create status = %d, want 401", code)
+	}
+	if code := d.do(t, "POST", "/api/deals", d.userToken, input, nil); code != http.StatusForbidden {
+		t.Errorf("user create status = %d, want 403", code)
+	}
+
+	deal := d.createDeal(t, input)
+	if deal.Status != models.DealStatusActive || deal.Currency != "USD" || deal.BillingCycle != models.BillingMonthly {
+		t.Errorf("created deal = %+v", deal)
+	}
+	if code := d.do(t, "POST", "/api/deals", d.adminToken, service.DealInput{Title: "KVM", Price: -1}, nil); code != http.StatusBadRequest {
+		t.Errorf("negative price status = %d, want 400", code)
+	}
+
+	target := fmt.Sprintf("/api/deals/%d", deal.ID)
+	var resp struct {
+		Deal models.Deal `json:"deal"`
+	}
+	update := service.DealInput{Title: "KVM Pro", Price: 12, BillingCycle: models.BillingQuarterly}
+	if code := d.do(t, "PUT", target, d.adminToken, update, &resp); code != http.StatusOK {
+		t.Fatalf("update status = %d", code)
+	}
+	if resp.Deal.Title != "KVM Pro" || resp.Deal.MonthlyPrice != 4 {
+		t.Errorf("updated deal = %+v", resp.Deal)
+	}
+	if code := d.do(t, "PUT", "/api/deals/999", d.adminToken, update, nil); code != http.StatusNotFound {
+		t.Errorf("update missing status = %d, want 404", code)
+	}
+
+	if code := d.do(t, "DELETE", target, d.userToken, nil, nil); code != http.StatusForbidden {
+		t.Errorf("user delete status = %d, want 403", code)
+	}
+	if code := d.do(t, "DELETE", target, d.adminToken, nil, nil); code != http.StatusOK {
+		t.Fatalf("delete status = %d", code)
+	}
+	if code := d.do(t, "GET", target, "", nil, nil); code != http.StatusNotFound {
+		t.Errorf("deleted deal status = %d, want 404", code)
+	}
+	if code := d.do(t, "DELETE", target, d.adminToken, nil, nil); code != http.StatusNotFound {
+		t.Errorf("delete again status = %d, want 404", code)
+	}
+}
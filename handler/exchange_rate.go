@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"go-nextjs/config"
+	"go-nextjs/service"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxRateFileSize 汇率文件的大小上限
+const maxRateFileSize = 2 << 20
+
+// ListExchangeRates 获取当前基准货币的汇率
+func ListExchangeRates(c *gin.Context) {
+	rates, err := service.ListExchangeRates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取汇率失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"base": config.BaseCurrency, "rates": rates})
+}
+
+// SetExchangeRate 设置货币的汇率，rate为1单位基准货币可兑换的该货币数量
+func SetExchangeRate(c *gin.Context) {
+	var req struct {
+		Rate float64 `json:"rate" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	rate, deals, err := service.SetExchangeRate(c.Param("currency"), req.Rate)
+	if err != nil {
+		respondRateError(c, err, "设置汇率失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rate": rate, "deals": deals})
+}
+
+// DeleteExchangeRate 删除货币的汇率
+func DeleteExchangeRate(c *gin.Context) {
+	deals, err := service.DeleteExchangeRate(c.Param("currency"))
+	if err != nil {
+		respondRateError(c, err, "删除汇率失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功", "deals": deals})
+}
+
+// ImportExchangeRates 导入汇率文件，支持multipart上传的file字段或直接作为请求体
+//
+// format参数可选 csv、ecb，为空时根据文件内容判断。
+func ImportExchangeRates(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRateFileSize)
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请上传汇率文件"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "读取汇率文件失败"})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	result, err := service.ImportExchangeRates(body, c.Query("format"))
+	if err != nil {
+		respondRateError(c, err, "导入汇率失败")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// respondRateError 根据错误类型返回对应的状态码
+func respondRateError(c *gin.Context, err error, message string) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "汇率不存在"})
+	case errors.As(err, &maxBytesErr):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "汇率文件过大"})
+	case errors.Is(err, service.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
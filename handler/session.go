@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"go-nextjs/models"
+	"go-nextjs/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListMySessions 获取当前用户的有效会话
+func ListMySessions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	respondSessions(c, userID)
+}
+
+// RevokeMySession 吊销当前用户自己的某个会话
+func RevokeMySession(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	revokeSession(c, userID)
+}
+
+// ListUserSessions 管理员获取指定用户的有效会话
+func ListUserSessions(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+	respondSessions(c, uint(userID))
+}
+
+// RevokeUserSessions 管理员吊销指定用户的全部会话
+func RevokeUserSessions(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	count, err := service.RevokeUserSessions(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "吊销会话失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已吊销用户的所有会话", "revoked": count})
+}
+
+// RevokeAnySession 管理员吊销任意会话
+func RevokeAnySession(c *gin.Context) {
+	revokeSession(c, 0)
+}
+
+// respondSessions 返回用户的会话列表，并标记当前会话
+func respondSessions(c *gin.Context, userID uint) {
+	sessions, err := service.ListUserSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取会话列表失败"})
+		return
+	}
+
+	currentSID := c.GetString("session_id")
+	list := make([]models.SessionInfo, 0, len(sessions))
+	for i := range sessions {
+		info := sessions[i].ToSessionInfo()
+		info.Current = sessions[i].SID == currentSID
+		list = append(list, info)
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": list})
+}
+
+// revokeSession 根据路由中的会话ID吊销会话，userID为0时不限制所属用户
+func revokeSession(c *gin.Context, userID uint) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return
+	}
+
+	if err := service.RevokeSessionByID(uint(id), userID); err != nil {
+		if errors.Is(err, service.ErrSessionInvalid) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "吊销会话失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "会话已吊销"})
+}
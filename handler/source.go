@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"errors"
+	"go-nextjs/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListSources 获取数据来源列表
+func ListSources(c *gin.Context) {
+	sources, err := service.ListSources()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取数据来源失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sources": sources})
+}
+
+// CreateSource 创建数据来源
+func CreateSource(c *gin.Context) {
+	var input service.SourceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	source, err := service.CreateSource(&input)
+	if err != nil {
+		respondSourceError(c, err, "创建数据来源失败")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"source": source})
+}
+
+// UpdateSource 更新数据来源
+func UpdateSource(c *gin.Context) {
+	id, ok := sourceID(c)
+	if !ok {
+		return
+	}
+
+	var input service.SourceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	source, err := service.UpdateSource(id, &input)
+	if err != nil {
+		respondSourceError(c, err, "更新数据来源失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"source": source})
+}
+
+// DeleteSource 删除数据来源
+func DeleteSource(c *gin.Context) {
+	id, ok := sourceID(c)
+	if !ok {
+		return
+	}
+
+	if err := service.DeleteSource(id); err != nil {
+		respondSourceError(c, err, "删除数据来源失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// SyncSource 立即同步数据来源
+func SyncSource(c *gin.Context) {
+	id, ok := sourceID(c)
+	if !ok {
+		return
+	}
+
+	report, err := service.SyncSource(c.Request.Context(), id)
+	if err != nil {
+		respondSourceError(c, err, "同步失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// PreviewSource 按请求中的规则试抓取，不保存来源
+func PreviewSource(c *gin.Context) {
+	var input service.SourceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	items, err := service.PreviewSource(c.Request.Context(), &input)
+	if err != nil {
+		respondSourceError(c, err, "预览失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// PreviewStoredSource 按已保存的规则试抓取
+func PreviewStoredSource(c *gin.Context) {
+	id, ok := sourceID(c)
+	if !ok {
+		return
+	}
+
+	items, err := service.PreviewStoredSource(c.Request.Context(), id)
+	if err != nil {
+		respondSourceError(c, err, "预览失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// sourceID 解析路由中的来源ID，失败时直接返回400
+func sourceID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的来源ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondSourceError 根据错误类型返回对应的状态码
+func respondSourceError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "数据来源不存在"})
+	case errors.Is(err, service.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSourceSyncing):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSourceFetch):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"go-nextjs/models"
+	"go-nextjs/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UpdateRoleRequest 修改角色请求
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ListUsers 获取用户列表
+func ListUsers(c *gin.Context) {
+	users, err := service.ListUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户列表失败"})
+		return
+	}
+
+	list := make([]models.UserInfo, 0, len(users))
+	for i := range users {
+		list = append(list, users[i].ToUserInfo())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": list})
+}
+
+// UpdateUserRole 提升或降级用户角色
+func UpdateUserRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	if req.Role != models.RoleAdmin && req.Role != models.RoleUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的角色"})
+		return
+	}
+
+	user, err := service.SetUserRole(uint(id), req.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		case errors.Is(err, service.ErrLastAdmin):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "修改角色失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user.ToUserInfo()})
+}
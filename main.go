@@ -6,7 +6,10 @@ import (
 
 	"go-nextjs/config"
 	"go-nextjs/cron"
+	"go-nextjs/pkg/jwk"
+	"go-nextjs/pkg/oauth"
 	"go-nextjs/router"
+	"go-nextjs/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,6 +28,26 @@ func main() {
 		log.Fatalf("数据库连接未正确初始化")
 	}
 
+	// 按最新的解析规则回填优惠的数值字段
+	if n, err := service.RecomputeDealMetrics(); err != nil {
+		log.Printf("回填优惠规格失败: %v", err)
+	} else if n > 0 {
+		log.Printf("已重新计算 %d 条优惠的规格", n)
+	}
+
+	// 启用AI结果缓存
+	service.InitAICache()
+
+	// 初始化JWT签名密钥
+	if err := jwk.Init(); err != nil {
+		log.Fatalf("初始化JWT签名密钥失败: %v", err)
+	}
+
+	// 初始化登录提供商
+	if err := oauth.Init(); err != nil {
+		log.Fatalf("初始化登录提供商失败: %v", err)
+	}
+
 	// 初始化定时任务
 	if err := cron.Init(); err != nil {
 		log.Fatalf("初始化定时任务失败: %v", err)
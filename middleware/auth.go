@@ -1,11 +1,15 @@
 package middleware
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"go-nextjs/config"
-	"io"
+	"go-nextjs/models"
+	"go-nextjs/pkg/jwk"
+	"go-nextjs/pkg/oauth"
+	"go-nextjs/service"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,21 +17,11 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
-// TokenResponse OAuth2 token响应
-type TokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	TokenType    string `json:"token_type"`
-	ExpiresIn    int    `json:"expires_in"`
+// TokenPair 登录或刷新后返回的令牌
+type TokenPair struct {
+	AccessToken  string `json:"token"`
 	RefreshToken string `json:"refresh_token"`
-}
-
-// UserInfo CZL Connect用户信息
-type UserInfo struct {
-	ID       uint   `json:"id"`
-	Username string `json:"username"`
-	Nickname string `json:"nickname"`
-	Email    string `json:"email"`
-	Avatar   string `json:"avatar"`
+	ExpiresIn    int64  `json:"expires_in"` // 访问令牌有效期（秒）
 }
 
 // Claims JWT claims
@@ -38,26 +32,38 @@ type Claims struct {
 	jwt.StandardClaims
 }
 
-// AuthRequired 需要认证的中间件
+// AuthRequired 需要认证的中间件，支持Bearer header，Cookie模式下也接受Cookie中的令牌
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var token string
+
 		// 获取Authorization header
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "未提供认证信息"})
-			c.Abort()
-			return
+		if authHeader != "" {
+			// 检查Bearer token
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "认证格式错误"})
+				c.Abort()
+				return
+			}
+			token = parts[1]
+		} else if config.AuthCookieMode {
+			// 浏览器会自动携带Cookie，修改类请求必须通过CSRF校验
+			token, _ = c.Cookie(AccessTokenCookie)
+			if token != "" && !VerifyCSRF(c) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "CSRF校验失败"})
+				c.Abort()
+				return
+			}
 		}
 
-		// 检查Bearer token
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "认证格式错误"})
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未提供认证信息"})
 			c.Abort()
 			return
 		}
 
-		token := parts[1]
 		// 验证JWT token
 		claims, err := validateToken(token)
 		if err != nil {
@@ -66,8 +72,16 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		// 校验token所属会话是否仍然有效，登出或被吊销后立即失效
+		if _, err := service.ValidateSession(claims.Id); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
 		// 将用户信息存储在上下文中
 		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.Id)
 		c.Set("email", claims.Email)
 		c.Set("role", claims.Role)
 		c.Set("user", map[string]interface{}{
@@ -79,19 +93,34 @@ func AuthRequired() gin.HandlerFunc {
 	}
 }
 
-// AdminRequired 需要管理员权限的中间件
+// AdminRequired 需要管理员权限的中间件，必须在AuthRequired之后使用
 func AdminRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 获取用户角色
-		role, exists := c.Get("role")
+		// 获取用户ID
+		userID, exists := c.Get("user_id")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
 			c.Abort()
 			return
 		}
 
+		// 从数据库读取当前角色，角色变更后无需等待token过期即可生效
+		id, err := strconv.ParseUint(fmt.Sprint(userID), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
+			c.Abort()
+			return
+		}
+		user, err := service.GetUserByID(uint(id))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+			c.Abort()
+			return
+		}
+		c.Set("role", user.Role)
+
 		// 验证是否是管理员
-		if role != "admin" {
+		if user.Role != models.RoleAdmin {
 			c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
 			c.Abort()
 			return
@@ -101,212 +130,122 @@ func AdminRequired() gin.HandlerFunc {
 	}
 }
 
-// GetLoginURL 获取登录URL
-func GetLoginURL(redirectURI string) string {
-	// 使用固定值，避免配置问题
-	authURL := "https://connect.czl.net/oauth2/authorize"
-	clientID := "client_52xxx9"
+// HandleCallback 处理OAuth2回调，使用登录时保存的参数换取令牌，创建会话并签发JWT和刷新令牌
+func HandleCallback(ctx context.Context, provider oauth.Provider, code string, params oauth.AuthParams, meta service.SessionMeta) (*TokenPair, error) {
+	user, err := AuthenticateCallback(ctx, provider, code, params)
+	if err != nil {
+		return nil, err
+	}
 
-	return authURL + "?client_id=" + clientID +
-		"&response_type=code" +
-		"&redirect_uri=" + redirectURI +
-		"&scope=openid+profile+email"
-}
+	// 创建会话，生成JWT token和刷新令牌
+	tokens, err := IssueTokens(user, meta)
+	if err != nil {
+		fmt.Printf("生成token失败: %v\n", err)
+		return nil, fmt.Errorf("生成token失败: %v", err)
+	}
 
-// GetLoginURLWithState 获取带状态的登录URL
-func GetLoginURLWithState(redirectURI string, state string) string {
-	// 使用固定值，避免配置问题
-	authURL := "https://connect.czl.net/oauth2/authorize"
-	clientID := "client_52xxx869"
-
-	return authURL + "?client_id=" + clientID +
-		"&response_type=code" +
-		"&redirect_uri=" + redirectURI +
-		"&scope=openid+profile+email" +
-		"&state=" + state
+	return tokens, nil
 }
 
-// HandleCallback 处理OAuth2回调
-func HandleCallback(code string, redirectURI string) (string, error) {
-	// 添加调试日志
-	fmt.Printf("开始处理回调，code: %s, redirectURI: %s\n", code, redirectURI)
+// AuthenticateCallback 处理OAuth2回调，使用登录时保存的参数换取令牌并创建或更新本地用户，不签发令牌
+func AuthenticateCallback(ctx context.Context, provider oauth.Provider, code string, params oauth.AuthParams) (*models.User, error) {
+	fmt.Printf("开始处理 %s 回调\n", provider.Name())
 
 	// 1. 使用code获取access token
-	tokenResp, err := getAccessToken(code, redirectURI)
+	tokenResp, err := provider.Exchange(ctx, code, params)
 	if err != nil {
 		fmt.Printf("获取access token失败: %v\n", err)
-		return "", fmt.Errorf("获取access token失败: %v", err)
-	}
-
-	if tokenResp == nil || tokenResp.AccessToken == "" {
-		return "", fmt.Errorf("获取到的token为空")
+		return nil, fmt.Errorf("获取access token失败: %v", err)
 	}
 
-	fmt.Printf("获取access token成功: %s...\n", tokenResp.AccessToken[:10]) // 只显示token的前几位
-
 	// 2. 使用access token获取用户信息
-	userInfo, err := getUserInfo(tokenResp.AccessToken)
+	userInfo, err := provider.UserInfo(ctx, tokenResp)
 	if err != nil {
 		fmt.Printf("获取用户信息失败: %v\n", err)
-		return "", fmt.Errorf("获取用户信息失败: %v", err)
+		return nil, fmt.Errorf("获取用户信息失败: %v", err)
 	}
 
-	if userInfo == nil {
-		return "", fmt.Errorf("获取到的用户信息为空")
-	}
-
-	fmt.Printf("获取用户信息成功: %s, %s\n", userInfo.Username, userInfo.Email)
-
-	// 3. 生成JWT token
-	token, err := generateToken(userInfo)
+	// 3. 创建或更新本地用户
+	user, err := service.UpsertOAuthUser(&service.OAuthProfile{
+		Provider:    provider.Name(),
+		ExternID:    userInfo.ID,
+		Username:    userInfo.Username,
+		Nickname:    userInfo.Nickname,
+		Email:       userInfo.Email,
+		Avatar:      userInfo.Avatar,
+		AccessToken: tokenResp.AccessToken,
+
+		EmailVerified: userInfo.EmailVerified,
+	})
 	if err != nil {
-		fmt.Printf("生成token失败: %v\n", err)
-		return "", fmt.Errorf("生成token失败: %v", err)
+		fmt.Printf("保存用户失败: %v\n", err)
+		return nil, err
 	}
-	fmt.Printf("生成JWT token成功，长度: %d\n", len(token))
+	fmt.Printf("本地用户ID: %d\n", user.ID)
 
-	return token, nil
+	return user, nil
 }
 
-// getAccessToken 获取access token
-func getAccessToken(code, redirectURI string) (*TokenResponse, error) {
-	// 添加调试信息
-	fmt.Printf("开始获取access token，code: %s, redirectURI: %s\n", code, redirectURI)
-
-	// 使用固定值而不是配置，确保一致性
-	url := "https://connect.czl.net/api/oauth2/token"
-	clientID := "client_52xxx869"
-	clientSecret := "a6d9732x"
-	// 构建请求体
-	rawBody := fmt.Sprintf("grant_type=authorization_code&code=%s&redirect_uri=%s&client_id=%s&client_secret=%s",
-		code, redirectURI, clientID, clientSecret)
-
-	fmt.Printf("发送请求体: %s\n", rawBody)
-	fmt.Printf("请求URL: %s\n", url)
-
-	// 创建请求
-	req, err := http.NewRequest("POST", url, strings.NewReader(rawBody))
-	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Accept", "application/json")
-
-	// 发送请求
-	client := &http.Client{Timeout: 20 * time.Second}
-	resp, err := client.Do(req)
+// IssueTokens 为用户创建新会话，并签发访问令牌和刷新令牌
+func IssueTokens(user *models.User, meta service.SessionMeta) (*TokenPair, error) {
+	session, refreshToken, err := service.StartSession(user.ID, meta)
 	if err != nil {
-		return nil, fmt.Errorf("请求失败: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	// 读取响应体
-	respBody, err := io.ReadAll(resp.Body)
+	accessToken, err := generateToken(user, session.SID)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
-	}
-
-	fmt.Printf("响应状态码: %d\n", resp.StatusCode)
-	fmt.Printf("响应体: %s\n", string(respBody))
-
-	// 检查响应状态
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("请求返回非200状态码: %d, 响应: %s", resp.StatusCode, string(respBody))
-	}
-
-	// 解析响应
-	var tokenResp TokenResponse
-	if err := json.Unmarshal(respBody, &tokenResp); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
-	}
-
-	// 验证token
-	if tokenResp.AccessToken == "" {
-		return nil, fmt.Errorf("返回的access_token为空")
+		return nil, err
 	}
 
-	return &tokenResp, nil
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(config.JWTExpire.Seconds()),
+	}, nil
 }
 
-// getUserInfo 获取用户信息
-func getUserInfo(accessToken string) (*UserInfo, error) {
-	// 固定的用户信息URL
-	userInfoURL := "https://connect.czl.net/api/oauth2/userinfo"
-
-	// 创建请求
-	req, err := http.NewRequest("GET", userInfoURL, nil)
+// RefreshTokens 轮换刷新令牌并签发新的访问令牌
+func RefreshTokens(refreshToken string) (*TokenPair, error) {
+	user, session, newRefreshToken, err := service.RotateRefreshToken(refreshToken)
 	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	// 添加Authorization header
-	req.Header.Add("Authorization", "Bearer "+accessToken)
-	req.Header.Add("Accept", "application/json")
-
-	// 发送请求
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("请求用户信息失败: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	// 读取响应体
-	respBody, err := io.ReadAll(resp.Body)
+	// 使用数据库中的最新角色签发访问令牌
+	accessToken, err := generateToken(user, session.SID)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
-	}
-
-	fmt.Printf("用户信息响应状态码: %d\n", resp.StatusCode)
-	fmt.Printf("用户信息响应体: %s\n", string(respBody))
-
-	// 检查响应状态
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("用户信息请求返回非200状态码: %d, 响应: %s", resp.StatusCode, string(respBody))
-	}
-
-	// 解析响应
-	var userInfo UserInfo
-	if err := json.Unmarshal(respBody, &userInfo); err != nil {
-		return nil, fmt.Errorf("解析用户信息失败: %v", err)
-	}
-
-	// 验证必要字段
-	if userInfo.ID == 0 {
-		return nil, fmt.Errorf("返回的用户ID为空")
+		return nil, err
 	}
 
-	// 打印解析后的用户信息
-	fmt.Printf("成功解析用户信息: ID=%d, Username=%s, Email=%s\n",
-		userInfo.ID, userInfo.Username, userInfo.Email)
-
-	return &userInfo, nil
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+		ExpiresIn:    int64(config.JWTExpire.Seconds()),
+	}, nil
 }
 
-// generateToken 生成JWT token
-func generateToken(userInfo *UserInfo) (string, error) {
+// generateToken 生成JWT token，jti为所属会话的SID
+func generateToken(user *models.User, sessionID string) (string, error) {
 	claims := Claims{
-		UserID: fmt.Sprintf("%d", userInfo.ID), // 将uint转为string
-		Email:  userInfo.Email,
-		Role:   "admin", // 所有认证用户都是管理员
+		UserID: fmt.Sprintf("%d", user.ID), // 本地用户ID，将uint转为string
+		Email:  user.Email,
+		Role:   user.Role,
 		StandardClaims: jwt.StandardClaims{
-			// 设置较长的过期时间
-			ExpiresAt: time.Now().Add(30 * 24 * time.Hour).Unix(), // 30天
+			Id:     sessionID,
+			Issuer: config.SystemURL,
+			// 访问令牌有效期较短，过期后通过刷新令牌续期
+			ExpiresAt: time.Now().Add(config.JWTExpire).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.JWTSecret))
+	// 使用当前的非对称签名密钥，头部携带kid
+	return jwk.Sign(claims)
 }
 
 // validateToken 验证JWT token
 func validateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, jwk.Keyfunc)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"go-nextjs/pkg/jwk"
+	"go-nextjs/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupAuthRouter 准备内存数据库和签名密钥，返回受AuthRequired保护的路由及一个已登录用户的令牌
+func setupAuthRouter(t *testing.T) (*gin.Engine, *TokenPair) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接独立
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Session{}); err != nil {
+		t.Fatal(err)
+	}
+	config.DB = db
+
+	config.DataDir = t.TempDir()
+	config.JWTSecret = "test-secret"
+	config.JWTAlgorithm = "EdDSA"
+	config.JWTKeyRotation = 24 * time.Hour
+	config.JWTExpire = 15 * time.Minute
+	config.RefreshTokenExpire = 24 * time.Hour
+	config.AuthCookieMode = true
+	if err := jwk.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	user := &models.User{Username: "alice", Email: "alice@example.com", Role: models.RoleUser}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := IssueTokens(user, service.SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id")}) }
+	router.GET("/api/me", AuthRequired(), ok)
+	router.POST("/api/me", AuthRequired(), ok)
+	return router, tokens
+}
+
+// serve 发送请求并返回状态码
+func serve(router *gin.Engine, req *http.Request) int {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+// cookieRequest 以Cookie携带访问令牌和CSRF令牌，csrfHeader为空时不设置CSRF请求头
+func cookieRequest(method, accessToken, csrfHeader string) *http.Request {
+	req := httptest.NewRequest(method, "/api/me", nil)
+	req.AddCookie(&http.Cookie{Name: AccessTokenCookie, Value: accessToken})
+	req.AddCookie(&http.Cookie{Name: CSRFCookie, Value: "csrf-token"})
+	if csrfHeader != "" {
+		req.Header.Set(CSRFHeader, csrfHeader)
+	}
+	return req
+}
+
+func TestAuthRequiredCSRF(t *testing.T) {
+	router, tokens := setupAuthRouter(t)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"post without header", cookieRequest("POST", tokens.AccessToken, ""), http.StatusForbidden},
+		{"post with wrong header", cookieRequest("POST", tokens.AccessToken, "other"), http.StatusForbidden},
+		{"post with header", cookieRequest("POST", tokens.AccessToken, "csrf-token"), http.StatusOK},
+		{"get without header", cookieRequest("GET", tokens.AccessToken, ""), http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := serve(router, tt.req); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// Authorization请求头不会被浏览器自动携带，无需CSRF校验
+	req := httptest.NewRequest("POST", "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	if got := serve(router, req); got != http.StatusOK {
+		t.Errorf("bearer post: status = %d, want 200", got)
+	}
+}
+
+func TestAuthRequiredRevokedSession(t *testing.T) {
+	router, tokens := setupAuthRouter(t)
+	bearer := func() *http.Request {
+		req := httptest.NewRequest("GET", "/api/me", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		return req
+	}
+
+	if got := serve(router, bearer()); got != http.StatusOK {
+		t.Fatalf("status = %d, want 200", got)
+	}
+
+	claims, err := validateToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.RevokeSession(claims.Id); err != nil {
+		t.Fatal(err)
+	}
+	// 令牌本身未过期，但所属会话已吊销
+	if got := serve(router, bearer()); got != http.StatusUnauthorized {
+		t.Errorf("revoked session: status = %d, want 401", got)
+	}
+}
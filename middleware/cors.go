@@ -1,13 +1,25 @@
 package middleware
 
 import (
+	"go-nextjs/config"
+	"net/url"
+
 	"github.com/gin-gonic/gin"
 )
 
 // CORSMiddleware 处理跨域请求
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		// Cookie模式需要携带凭据，不能使用通配符，只允许前端域名
+		if config.AuthCookieMode {
+			origin := c.GetHeader("Origin")
+			if origin != "" && origin == allowedOrigin() {
+				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			c.Writer.Header().Add("Vary", "Origin")
+		} else {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		}
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
@@ -20,3 +32,12 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// allowedOrigin 从系统URL中提取允许跨域的源
+func allowedOrigin() string {
+	u, err := url.Parse(config.SystemURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// AICache AI调用结果的缓存
+type AICache struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	Key        string    `gorm:"size:64;not null;uniqueIndex" json:"key"` // sha256(模型, 提示词版本, 输入)
+	Kind       string    `gorm:"size:20;index" json:"kind"`               // 类型：vps、title
+	Model      string    `gorm:"size:100" json:"model"`                   // 生成结果的模型
+	Value      string    `gorm:"type:text" json:"value"`                  // 结果
+	HitCount   int       `json:"hit_count"`                               // 命中次数
+	LastUsedAt time.Time `gorm:"index" json:"last_used_at"`               // 上次写入或命中的时间，超出条数上限时最久未使用的先删除
+	ExpiresAt  time.Time `gorm:"index" json:"expires_at"`                 // 过期时间
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName AI缓存表名
+func (AICache) TableName() string {
+	return "ai_cache"
+}
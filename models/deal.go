@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 计费周期
+const (
+	BillingMonthly      = "monthly"      // 月付
+	BillingQuarterly    = "quarterly"    // 季付
+	BillingSemiannually = "semiannually" // 半年付
+	BillingAnnually     = "annually"     // 年付
+	BillingBiennially   = "biennially"   // 两年付
+	BillingTriennially  = "triennially"  // 三年付
+)
+
+// BillingCycleMonths 各计费周期对应的月数
+var BillingCycleMonths = map[string]int{
+	BillingMonthly:      1,
+	BillingQuarterly:    3,
+	BillingSemiannually: 6,
+	BillingAnnually:     12,
+	BillingBiennially:   24,
+	BillingTriennially:  36,
+}
+
+// 优惠状态
+const (
+	DealStatusActive   = "active"   // 有效
+	DealStatusSoldOut  = "sold_out" // 售罄
+	DealStatusExpired  = "expired"  // 已失效，如购买页面不存在
+	DealStatusArchived = "archived" // 已过结束时间，归档
+)
+
+// DealStatuses 所有有效的优惠状态
+var DealStatuses = []string{DealStatusActive, DealStatusSoldOut, DealStatusExpired, DealStatusArchived}
+
+// Deal VPS优惠信息
+type Deal struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	Title        string     `gorm:"size:300;not null" json:"title"`                                 // 标题
+	Provider     string     `gorm:"size:100;index" json:"provider"`                                 // 服务商
+	Price        float64    `gorm:"not null" json:"price"`                                          // 价格
+	Currency     string     `gorm:"size:10;default:USD" json:"currency"`                            // 货币
+	BillingCycle string     `gorm:"size:20;default:monthly" json:"billing_cycle"`                   // 计费周期
+	URL          string     `gorm:"size:1000" json:"url"`                                           // 购买链接
+	CPU          string     `gorm:"size:100" json:"cpu"`                                            // CPU
+	RAM          string     `gorm:"size:100" json:"ram"`                                            // 内存
+	Disk         string     `gorm:"size:100" json:"disk"`                                           // 硬盘
+	Bandwidth    string     `gorm:"size:200" json:"bandwidth"`                                      // 带宽/流量
+	IP           string     `gorm:"size:200" json:"ip"`                                             // IP
+	Location     string     `gorm:"size:500" json:"location"`                                       // 位置
+	Remark       string     `gorm:"type:text" json:"remark"`                                        // 备注
+	Status       string     `gorm:"size:20;default:active;index" json:"status"`                     // 状态
+	EndsAt       *time.Time `json:"ends_at"`                                                        // 优惠结束时间，为空表示未知
+	CheckedAt    *time.Time `json:"checked_at"`                                                     // 上次检查购买链接的时间
+	SourceID     *uint      `gorm:"uniqueIndex:idx_deal_source_extern" json:"source_id"`            // 数据来源，手动创建时为空
+	ExternalID   string     `gorm:"size:300;uniqueIndex:idx_deal_source_extern" json:"external_id"` // 来源中的条目ID
+
+	// 来源条目的AI分析失败时，之后同步到该条目时重新分析
+	EnrichPending  bool `gorm:"index" json:"enrich_pending"` // AI分析未完成，等待重试
+	EnrichAttempts int  `json:"-"`                           // AI分析的尝试次数
+
+	// 以下为根据配置文本和价格计算的数值字段，用于筛选和排序
+	CPUCores      int     `gorm:"index" json:"cpu_cores"`           // CPU核心数
+	RAMMB         int     `gorm:"index" json:"ram_mb"`              // 内存（MB）
+	DiskGB        int     `gorm:"index" json:"disk_gb"`             // 硬盘（GB）
+	DiskType      string  `gorm:"size:10" json:"disk_type"`         // 硬盘类型：NVMe、SSD、HDD
+	TrafficGB     int     `json:"traffic_gb"`                       // 每月流量（GB）
+	Unlimited     bool    `json:"unlimited_traffic"`                // 是否不限流量
+	PortMbps      int     `json:"port_mbps"`                        // 端口速率（Mbps）
+	IPv4Count     int     `json:"ipv4_count"`                       // IPv4数量
+	IPv6          bool    `json:"ipv6"`                             // 是否提供IPv6
+	MonthlyPrice  float64 `gorm:"index" json:"monthly_price"`       // 折算后的月付价格，货币与原价相同
+	PricePerGBRAM float64 `gorm:"index" json:"price_per_gb_ram"`    // 每GB内存的基准货币月付价格，内存或汇率未知时为0
+	Fingerprint   string  `gorm:"size:64;index" json:"fingerprint"` // 由服务商、规格、价格和位置计算的指纹，信息不足时为空
+
+	// 按汇率折算为基准货币的月付价格，用于跨货币比较和排序，缺少汇率时为空
+	BaseMonthlyPrice *float64 `gorm:"index" json:"base_monthly_price"`
+
+	// 跨来源去重：重复的优惠合并到主优惠，列表中只展示主优惠
+	CanonicalID *uint `gorm:"index" json:"canonical_id"` // 合并到的主优惠ID，为空表示自身是主优惠
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// 合并操作类型
+const (
+	MergeActionAuto    = "auto_merge" // 同步时按指纹自动合并
+	MergeActionMerge   = "merge"      // 管理员手动合并
+	MergeActionSplit   = "split"      // 管理员手动拆分
+	MergeActionPromote = "promote"    // 主优惠删除后由重复记录接替
+)
+
+// DealMergeLog 优惠合并与拆分的操作记录
+type DealMergeLog struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	Action      string    `gorm:"size:20;not null" json:"action"`     // 操作类型
+	CanonicalID uint      `gorm:"not null;index" json:"canonical_id"` // 主优惠ID
+	DealIDs     string    `gorm:"size:1000" json:"deal_ids"`          // 被合并或拆分的优惠ID，逗号分隔
+	Fingerprint string    `gorm:"size:64" json:"fingerprint"`         // 自动合并时匹配的指纹
+	UserID      *uint     `json:"user_id"`                            // 操作的管理员，自动合并时为空
+	Reason      string    `gorm:"size:500" json:"reason"`             // 原因
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// 优惠事件类型
+const (
+	DealEventPriceDrop = "price_drop" // 降价
+)
+
+// DealPriceHistory 优惠价格的变化记录，每次观察到价格变化时写入一条
+type DealPriceHistory struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	DealID       uint      `gorm:"not null;index" json:"deal_id"`
+	Price        float64   `json:"price"`                        // 价格
+	Currency     string    `gorm:"size:10" json:"currency"`      // 货币
+	BillingCycle string    `gorm:"size:20" json:"billing_cycle"` // 计费周期
+	MonthlyPrice float64   `json:"monthly_price"`                // 折算后的月付价格
+	ObservedAt   time.Time `gorm:"index" json:"observed_at"`     // 观察到该价格的时间
+}
+
+// TableName 价格历史表名
+func (DealPriceHistory) TableName() string {
+	return "deal_price_history"
+}
+
+// DealEvent 优惠相关的事件，供通知、订阅源等模块按ID顺序消费
+type DealEvent struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	DealID      uint      `gorm:"not null;index" json:"deal_id"`
+	Type        string    `gorm:"size:30;not null;index" json:"type"` // 事件类型
+	OldPrice    float64   `json:"old_price"`                          // 变化前的月付价格
+	NewPrice    float64   `json:"new_price"`                          // 变化后的月付价格
+	Currency    string    `gorm:"size:10" json:"currency"`            // 货币
+	DropPercent float64   `json:"drop_percent"`                       // 降价幅度（百分比）
+	CreatedAt   time.Time `json:"created_at"`
+}
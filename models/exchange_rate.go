@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// 汇率来源
+const (
+	RateSourceManual = "manual" // 管理员手动设置
+	RateSourceCSV    = "csv"    // CSV文件导入
+	RateSourceECB    = "ecb"    // 欧洲央行XML导入
+)
+
+// ExchangeRate 汇率，Rate表示1单位基准货币可兑换的该货币数量
+type ExchangeRate struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Base      string    `gorm:"size:10;not null;uniqueIndex:idx_rate_base_currency" json:"base"`     // 基准货币
+	Currency  string    `gorm:"size:10;not null;uniqueIndex:idx_rate_base_currency" json:"currency"` // 货币
+	Rate      float64   `gorm:"not null" json:"rate"`                                                // 汇率
+	Source    string    `gorm:"size:20" json:"source"`                                               // 来源
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
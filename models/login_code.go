@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginCode 一次性登录码，非Cookie模式下回调只携带登录码，由前端通过POST换取令牌
+type LoginCode struct {
+	ID        uint       `gorm:"primarykey"`
+	CodeHash  string     `gorm:"size:64;not null;unique"` // 登录码的SHA-256哈希，不保存明文
+	UserID    uint       `gorm:"not null"`                // 用户ID
+	ExpiresAt time.Time  `gorm:"index"`                   // 过期时间
+	UsedAt    *time.Time // 使用时间，为空表示尚未使用
+	CreatedAt time.Time
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// OAuthState OAuth2登录状态，用于防止登录CSRF和重放
+type OAuthState struct {
+	ID           uint       `gorm:"primarykey"`
+	State        string     `gorm:"size:200;not null;unique"` // 签名后的state值
+	Provider     string     `gorm:"size:50"`                  // 发起登录的提供商
+	ReturnPath   string     `gorm:"size:500"`                 // 登录完成后返回的前端路径
+	CodeVerifier string     `gorm:"size:128"`                 // PKCE校验码
+	Nonce        string     `gorm:"size:100"`                 // OIDC nonce
+	ExpiresAt    time.Time  `gorm:"index"`                    // 过期时间
+	UsedAt       *time.Time // 使用时间，为空表示尚未使用
+	CreatedAt    time.Time
+}
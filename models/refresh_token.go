@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken 刷新令牌，每次使用后轮换，同一登录产生的令牌属于同一个家族
+type RefreshToken struct {
+	ID        uint       `gorm:"primarykey"`
+	UserID    uint       `gorm:"index;not null"`          // 用户ID
+	TokenHash string     `gorm:"size:64;not null;unique"` // 令牌的SHA-256哈希，不保存明文
+	FamilyID  string     `gorm:"size:64;index;not null"`  // 令牌家族ID，即所属会话的SID
+	ExpiresAt time.Time  `gorm:"index"`                   // 过期时间
+	UsedAt    *time.Time // 轮换时间，已轮换的令牌再次出现视为重用
+	RevokedAt *time.Time // 吊销时间
+	CreatedAt time.Time
+}
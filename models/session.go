@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// Session 登录会话，访问令牌的jti即会话的SID，刷新令牌家族ID与SID一致
+type Session struct {
+	ID         uint       `gorm:"primarykey"`
+	SID        string     `gorm:"column:sid;size:64;not null;unique"` // 会话标识，写入JWT的jti
+	UserID     uint       `gorm:"index;not null"`                     // 用户ID
+	IP         string     `gorm:"size:64"`                            // 登录IP
+	UserAgent  string     `gorm:"size:500"`                           // 浏览器User-Agent
+	Device     string     `gorm:"size:100"`                           // 根据User-Agent识别的设备
+	LastSeenAt time.Time  // 最后活跃时间
+	ExpiresAt  time.Time  `gorm:"index"` // 过期时间，随刷新令牌轮换延长
+	RevokedAt  *time.Time // 吊销时间
+	CreatedAt  time.Time
+}
+
+// SessionInfo 会话信息响应
+type SessionInfo struct {
+	ID         uint      `json:"id"`
+	UserID     uint      `json:"user_id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Device     string    `json:"device"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"`
+}
+
+// ToSessionInfo 转换为会话信息响应
+func (s *Session) ToSessionInfo() SessionInfo {
+	return SessionInfo{
+		ID:         s.ID,
+		UserID:     s.UserID,
+		IP:         s.IP,
+		UserAgent:  s.UserAgent,
+		Device:     s.Device,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+		CreatedAt:  s.CreatedAt,
+	}
+}
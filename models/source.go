@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 数据来源类型
+const (
+	SourceTypeRSS  = "rss"  // RSS/Atom订阅
+	SourceTypeJSON = "json" // JSON接口
+	SourceTypeHTML = "html" // HTML页面
+)
+
+// SelectorRule HTML来源的CSS选择器规则
+//
+// 字段选择器相对于条目容器，可用 "选择器@属性" 读取属性，例如 "a.buy@href"；
+// 省略选择器（如 "@data-price"）时读取容器本身。
+type SelectorRule struct {
+	Item        string `gorm:"size:500" json:"item"`        // 条目容器
+	Title       string `gorm:"size:500" json:"title"`       // 标题
+	Price       string `gorm:"size:500" json:"price"`       // 价格
+	Link        string `gorm:"size:500" json:"link"`        // 购买链接，未指定属性时读取href
+	Description string `gorm:"size:500" json:"description"` // 描述
+}
+
+// Source 优惠信息的数据来源
+type Source struct {
+	ID           uint           `gorm:"primarykey" json:"id"`
+	Name         string         `gorm:"size:100;not null" json:"name"`                      // 名称
+	Type         string         `gorm:"size:20;not null" json:"type"`                       // 类型：rss、json、html
+	URL          string         `gorm:"size:1000;not null" json:"url"`                      // 抓取地址
+	Provider     string         `gorm:"size:100" json:"provider"`                           // 默认服务商，条目未提供时使用
+	Interval     int            `gorm:"not null;default:60" json:"interval"`                // 同步间隔（分钟）
+	Enabled      bool           `gorm:"not null" json:"enabled"`                            // 是否启用
+	LastSyncAt   *time.Time     `json:"last_sync_at"`                                       // 上次同步时间
+	NextSyncAt   time.Time      `gorm:"index" json:"next_sync_at"`                          // 下次同步时间
+	LastError    string         `gorm:"type:text" json:"last_error"`                        // 上次同步的错误信息，成功时清空
+	ErrorCount   int            `gorm:"not null;default:0" json:"error_count"`              // 连续失败次数
+	ItemCount    int            `gorm:"not null;default:0" json:"item_count"`               // 上次同步获取的条目数
+	Selectors    SelectorRule   `gorm:"embedded;embeddedPrefix:selector_" json:"selectors"` // HTML来源的抓取规则
+	ETag         string         `gorm:"column:etag;size:200" json:"-"`                      // 上次响应的ETag，用于条件请求
+	LastModified string         `gorm:"size:100" json:"-"`                                  // 上次响应的Last-Modified，用于条件请求
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -4,18 +4,27 @@ import (
 	"gorm.io/gorm"
 )
 
+// ProviderCZLConnect CZL Connect 登录提供商
+const ProviderCZLConnect = "czl_connect"
+
+// 用户角色
+const (
+	RoleAdmin = "admin" // 管理员
+	RoleUser  = "user"  // 普通用户
+)
+
 // User 用户模型
 type User struct {
 	gorm.Model
-	Username  string `gorm:"size:100;not null;unique"` // 用户名
-	Nickname  string `gorm:"size:100"`                 // 昵称
-	Email     string `gorm:"size:100"`                 // 邮箱
-	Avatar    string `gorm:"size:500"`                 // 头像
-	Role      string `gorm:"size:20;default:user"`     // 角色：admin, user
-	ExternID  string `gorm:"size:100;unique"`          // 外部ID
-	Provider  string `gorm:"size:20"`                  // 提供商：czl_connect
-	LastLogin int64  `gorm:"default:0"`                // 最后登录时间
-	Token     string `gorm:"size:500"`                 // 访问令牌
+	Username  string `gorm:"size:100;not null;unique"`                      // 用户名
+	Nickname  string `gorm:"size:100"`                                      // 昵称
+	Email     string `gorm:"size:100"`                                      // 邮箱
+	Avatar    string `gorm:"size:500"`                                      // 头像
+	Role      string `gorm:"size:20;default:user"`                          // 角色：admin, user
+	ExternID  string `gorm:"size:100;uniqueIndex:idx_user_provider_extern"` // 外部ID
+	Provider  string `gorm:"size:50;uniqueIndex:idx_user_provider_extern"`  // 提供商：czl_connect, github, google 等
+	LastLogin int64  `gorm:"default:0"`                                     // 最后登录时间
+	Token     string `gorm:"size:500"`                                      // 访问令牌
 }
 
 // UserInfo 用户信息响应
@@ -27,3 +36,15 @@ type UserInfo struct {
 	Avatar   string `json:"avatar"`
 	Role     string `json:"role"`
 }
+
+// ToUserInfo 转换为用户信息响应
+func (u *User) ToUserInfo() UserInfo {
+	return UserInfo{
+		ID:       u.ID,
+		Username: u.Username,
+		Nickname: u.Nickname,
+		Email:    u.Email,
+		Avatar:   u.Avatar,
+		Role:     u.Role,
+	}
+}
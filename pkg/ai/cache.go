@@ -0,0 +1,60 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// 缓存条目的类型
+const (
+	CacheKindVPS   = "vps"   // 配置提取
+	CacheKindTitle = "title" // 标题优化
+)
+
+// 提示词版本，修改提示词或schema后需要递增，使旧的缓存失效
+const (
+	vpsPromptVersion   = "vps-v1"
+	titlePromptVersion = "title-v1"
+)
+
+// CacheEntry 缓存的AI结果
+type CacheEntry struct {
+	Key   string // 由模型、提示词版本和输入计算的哈希
+	Kind  string // 条目类型
+	Model string // 生成结果的模型
+	Value []byte // 结果
+}
+
+// Cache AI结果的缓存，实现需可并发使用，自行处理过期
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, entry *CacheEntry)
+}
+
+// SetDefaultCache 设置默认客户端使用的缓存，需在开始调用AI之前设置
+func SetDefaultCache(cache Cache) {
+	defaultClient().Cache = cache
+}
+
+// cacheKey 计算缓存键：sha256(模型, 提示词版本, 输入)
+func cacheKey(model, version, input string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + version + "\x00" + input))
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheGet 读取缓存，未设置缓存时视为未命中
+func (c *Client) cacheGet(ctx context.Context, key string) ([]byte, bool) {
+	if c.Cache == nil {
+		return nil, false
+	}
+	return c.Cache.Get(ctx, key)
+}
+
+// cacheSet 写入缓存，未设置缓存时忽略
+func (c *Client) cacheSet(ctx context.Context, kind, key string, value []byte) {
+	if c.Cache == nil {
+		return
+	}
+	c.Cache.Set(ctx, &CacheEntry{Key: key, Kind: kind, Model: c.Model, Value: value})
+}
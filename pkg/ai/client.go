@@ -0,0 +1,294 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-nextjs/config"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// 重试参数的默认值
+const (
+	defaultMaxRetries    = 3
+	defaultBaseDelay     = 500 * time.Millisecond
+	defaultMaxDelay      = 30 * time.Second
+	defaultClientTimeout = 60 * time.Second
+	defaultConcurrency   = 4
+	// maxErrorBodySize 读取错误响应体的上限
+	maxErrorBodySize = 64 << 10
+)
+
+// errDecode 响应格式错误，重试也无法恢复
+var errDecode = errors.New("解析AI响应失败")
+
+// sharedTransport 所有客户端共用的连接池
+var sharedTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   20,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+// APIError AI服务返回的错误
+type APIError struct {
+	StatusCode int           // HTTP状态码
+	Type       string        // 错误类型，如 rate_limit_error
+	Message    string        // 错误信息，响应不是标准格式时为响应体的开头部分
+	RetryAfter time.Duration // 服务端要求的等待时间，未提供时为0
+}
+
+// Error 实现error接口
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("AI服务返回错误 %d (%s): %s", e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("AI服务返回错误 %d: %s", e.StatusCode, e.Message)
+}
+
+// Temporary 是否为可重试的错误：限流或服务端错误
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Client OpenAI兼容接口的客户端，可并发使用
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	Model      string
+	HTTPClient *http.Client
+
+	MaxRetries int           // 限流或服务端错误时的最大重试次数
+	BaseDelay  time.Duration // 第一次重试前的等待时间，之后按指数增长
+	MaxDelay   time.Duration // 单次等待时间的上限，Retry-After超过该值时不再重试
+
+	Concurrency int      // 批量请求时的最大并发数
+	Limiter     *Limiter // 请求限流器，为nil时不限流
+	Cache       Cache    // 结果缓存，为nil时不缓存
+
+	outputMode atomic.Int32 // 结构化输出的方式，服务不支持时降级
+}
+
+// NewClient 创建客户端，使用共享的连接池和默认的重试参数
+func NewClient(baseURL, apiKey, model string) *Client {
+	return &Client{
+		BaseURL:     strings.TrimSuffix(baseURL, "/"),
+		APIKey:      apiKey,
+		Model:       model,
+		HTTPClient:  &http.Client{Transport: sharedTransport, Timeout: defaultClientTimeout},
+		MaxRetries:  defaultMaxRetries,
+		BaseDelay:   defaultBaseDelay,
+		MaxDelay:    defaultMaxDelay,
+		Concurrency: defaultConcurrency,
+	}
+}
+
+var (
+	defaultOnce  sync.Once
+	sharedClient *Client
+)
+
+// defaultClient 根据配置创建的共享客户端，限流器和结构化输出的降级状态在所有调用之间共享
+func defaultClient() *Client {
+	defaultOnce.Do(func() {
+		sharedClient = NewClient(config.AIURL, config.AIAPIKey, config.AIModel)
+		sharedClient.Concurrency = config.AIConcurrency
+		sharedClient.Limiter = NewLimiter(config.AIRequestsPerMinute, config.AITokensPerMinute)
+	})
+	return sharedClient
+}
+
+// Chat 发送聊天请求，限流、服务端错误和网络错误时按指数退避重试
+//
+// 请求未指定模型时使用客户端的模型。
+func (c *Client) Chat(ctx context.Context, chatReq *ChatRequest) (*ChatResponse, error) {
+	if c.APIKey == "" {
+		return nil, fmt.Errorf("未配置AI API密钥")
+	}
+	if chatReq.Model == "" {
+		chatReq.Model = c.Model
+	}
+
+	jsonData, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("序列化AI请求失败: %v", err)
+	}
+
+	estimated := estimateTokens(chatReq)
+	for attempt := 0; ; attempt++ {
+		// 每次重试都计入限流
+		if err := c.Limiter.Wait(ctx, estimated); err != nil {
+			return nil, err
+		}
+		chatResp, err := c.do(ctx, jsonData)
+		if err == nil {
+			c.Limiter.Adjust(estimated, chatResp.Usage.TotalTokens)
+			return chatResp, nil
+		}
+		if attempt >= c.MaxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		var delay time.Duration
+		var apiErr *APIError
+		switch {
+		case errors.As(err, &apiErr):
+			if !apiErr.Temporary() {
+				return nil, err
+			}
+			delay = apiErr.RetryAfter
+			if delay > c.MaxDelay {
+				return nil, err
+			}
+			if delay == 0 {
+				delay = c.backoff(attempt)
+			}
+		case errors.Is(err, errDecode):
+			return nil, err
+		default:
+			// 网络错误
+			delay = c.backoff(attempt)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// do 发送一次请求
+func (c *Client) do(ctx context.Context, jsonData []byte) (*ChatResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/chat/completions", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建AI请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("AI请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取AI响应失败: %w", err)
+	}
+
+	var chatResp ChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return nil, fmt.Errorf("%w: %v", errDecode, err)
+	}
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("%w: 响应中没有选择项", errDecode)
+	}
+	return &chatResp, nil
+}
+
+// estimateTokens 粗略估算请求消耗的token数，用于发送前限流：
+// ASCII字符按4个一个token，其他字符按1个一个token，加上最大输出长度
+func estimateTokens(chatReq *ChatRequest) int {
+	ascii, other := 0, 0
+	for _, msg := range chatReq.Messages {
+		for _, r := range msg.Content {
+			if r < utf8.RuneSelf {
+				ascii++
+			} else {
+				other++
+			}
+		}
+	}
+	return ascii/4 + other + 4*len(chatReq.Messages) + chatReq.MaxTokens
+}
+
+// backoff 计算第attempt次重试前的等待时间，在指数退避的基础上加入随机抖动
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.BaseDelay << attempt
+	if delay <= 0 || delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	// 等待时间在 [delay/2, delay) 之间随机，避免多个请求同时重试
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half)
+}
+
+// newAPIError 读取错误响应，解析OpenAI格式的错误信息和Retry-After
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error.Message != "" {
+		apiErr.Message = payload.Error.Message
+		apiErr.Type = payload.Error.Type
+		return apiErr
+	}
+
+	message := strings.TrimSpace(string(body))
+	if runes := []rune(message); len(runes) > 200 {
+		message = string(runes[:200]) + "..."
+	}
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+	apiErr.Message = message
+	return apiErr
+}
+
+// parseRetryAfter 解析Retry-After，支持秒数和HTTP日期两种格式
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter 按每分钟请求数和每分钟token数限制AI请求，可在多个客户端之间共享
+type Limiter struct {
+	requests *tokenBucket
+	tokens   *tokenBucket
+}
+
+// NewLimiter 创建限流器，rpm为每分钟请求数，tpm为每分钟token数，不大于0表示不限制
+func NewLimiter(rpm, tpm int) *Limiter {
+	return &Limiter{
+		requests: newTokenBucket(rpm),
+		tokens:   newTokenBucket(tpm),
+	}
+}
+
+// Wait 等待到可以发送一个预计消耗n个token的请求，ctx取消时返回其错误
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	if err := l.requests.wait(ctx, 1); err != nil {
+		return err
+	}
+	return l.tokens.wait(ctx, n)
+}
+
+// Adjust 请求完成后按实际用量修正token桶，actual为实际消耗，estimated为Wait时的预估
+func (l *Limiter) Adjust(estimated, actual int) {
+	if l == nil || actual <= 0 {
+		return
+	}
+	l.tokens.adjust(estimated - actual)
+}
+
+// tokenBucket 令牌桶，容量为每分钟的配额，按速率匀速补充
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	rate     float64 // 每秒补充的令牌数
+	tokens   float64
+	last     time.Time
+}
+
+// newTokenBucket 创建每分钟perMinute个令牌的桶，不大于0时返回nil表示不限制
+func newTokenBucket(perMinute int) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		tokens:   float64(perMinute),
+		last:     time.Now(),
+	}
+}
+
+// wait 取出n个令牌，不足时等待补充；n超过容量时按容量计算，避免永远等待
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	if b == nil {
+		return nil
+	}
+	need := float64(n)
+	if need > b.capacity {
+		need = b.capacity
+	}
+
+	for {
+		b.mu.Lock()
+		b.refill()
+		if b.tokens >= need {
+			b.tokens -= need
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((need - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// adjust 归还或追加扣除令牌，扣除后可以为负数，之后的请求需等待补足
+func (b *tokenBucket) adjust(delta int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens += float64(delta)
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+}
+
+// refill 按经过的时间补充令牌，调用方需持有锁
+func (b *tokenBucket) refill() {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+}
@@ -0,0 +1,146 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// drain 清空令牌桶，之后只能按速率获取
+func drain(b *tokenBucket) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = 0
+	b.last = time.Now()
+}
+
+func TestOptimizeTitlesRespectsRequestRate(t *testing.T) {
+	f := newFakeChat(t)
+	f.reply = func(req *ChatRequest) (string, error) { return titleOf(req), nil }
+
+	// 每分钟600次即每100ms一次，清空初始配额后按速率发送
+	c := f.client()
+	c.Concurrency = 5
+	c.Limiter = NewLimiter(600, 0)
+	drain(c.Limiter.requests)
+
+	titles := make([]string, 5)
+	for i := range titles {
+		titles[i] = fmt.Sprint(i)
+	}
+	start := time.Now()
+	results := c.OptimizeTitles(context.Background(), titles)
+
+	for i, r := range results {
+		if r.Err != nil || r.Title != titles[i] {
+			t.Errorf("results[%d] = %+v", i, r)
+		}
+	}
+	f.mu.Lock()
+	arrivals := append([]time.Time(nil), f.arrivals...)
+	f.mu.Unlock()
+	if len(arrivals) != len(titles) {
+		t.Fatalf("requests = %d, want %d", len(arrivals), len(titles))
+	}
+	// 第k个请求最早在 k*100ms 时发出，留出少量计时误差
+	const interval = 100 * time.Millisecond
+	const slack = 15 * time.Millisecond
+	for k, at := range arrivals {
+		if min := time.Duration(k+1)*interval - slack; at.Sub(start) < min {
+			t.Errorf("request %d sent after %v, want at least %v", k+1, at.Sub(start), min)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Duration(len(titles))*interval+300*time.Millisecond {
+		t.Errorf("elapsed = %v, limiter too slow", elapsed)
+	}
+	// 并发数大于速率允许的数量时，同一时刻最多只有少量请求在处理
+	if got := atomic.LoadInt32(&f.maxInFlight); got > 2 {
+		t.Errorf("max in-flight requests = %d, want at most 2 under the rate limit", got)
+	}
+}
+
+func TestLimiterTokensPerMinute(t *testing.T) {
+	// 每分钟6000个token即每10ms 1个token
+	l := NewLimiter(0, 6000)
+	drain(l.tokens)
+
+	start := time.Now()
+	if err := l.Wait(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > 300*time.Millisecond {
+		t.Errorf("Wait(10) took %v, want about 100ms", elapsed)
+	}
+
+	// 实际用量少于预估时归还多扣的token，下一个请求无需等待
+	l.Adjust(50, 10)
+	start = time.Now()
+	if err := l.Wait(context.Background(), 40); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Millisecond {
+		t.Errorf("Wait after refund took %v, want no wait", elapsed)
+	}
+
+	// 实际用量超过预估时追加扣除，之后的请求需要等待补足
+	l.Adjust(0, 10)
+	start = time.Now()
+	if err := l.Wait(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Wait after overuse took %v, want about 110ms", elapsed)
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	t.Run("nil limiter", func(t *testing.T) {
+		var l *Limiter
+		if err := l.Wait(context.Background(), 1000); err != nil {
+			t.Fatal(err)
+		}
+		l.Adjust(1, 2)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		l := NewLimiter(0, 0)
+		for i := 0; i < 1000; i++ {
+			if err := l.Wait(context.Background(), 1000); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("burst up to capacity", func(t *testing.T) {
+		l := NewLimiter(60, 0)
+		start := time.Now()
+		for i := 0; i < 60; i++ {
+			if err := l.Wait(context.Background(), 1); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+			t.Errorf("burst took %v, want immediate", elapsed)
+		}
+	})
+
+	t.Run("request larger than capacity", func(t *testing.T) {
+		// 超过容量的请求按容量计算，不会永远等待
+		l := NewLimiter(0, 100)
+		if err := l.Wait(context.Background(), 1000); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		l := NewLimiter(1, 0)
+		drain(l.requests)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := l.Wait(ctx, 1); err != context.DeadlineExceeded {
+			t.Errorf("Wait() error = %v, want deadline exceeded", err)
+		}
+	})
+}
@@ -1,27 +1,23 @@
 package ai
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"go-nextjs/config"
-	"io"
 	"log"
-	"net/http"
 	"strings"
 	"sync"
-	"time"
 )
 
-// VPSConfig 表示从描述中提取的VPS配置信息
+// VPSConfig 表示从描述中提取的VPS配置信息，desc标签用于生成结构化输出的schema
 type VPSConfig struct {
-	CPU       string `json:"cpu"`
-	RAM       string `json:"ram"`
-	Disk      string `json:"disk"`
-	Bandwidth string `json:"bandwidth"`
-	IP        string `json:"ip"`
-	Location  string `json:"location"`
-	Remark    string `json:"remark"`
+	CPU       string `json:"cpu" desc:"CPU核心数，如 2 Core"`
+	RAM       string `json:"ram" desc:"内存大小，如 4GB RAM"`
+	Disk      string `json:"disk" desc:"硬盘容量及类型，如 50GB SSD"`
+	Bandwidth string `json:"bandwidth" desc:"流量和端口速度，如 500GB Traffic@1Gbps port"`
+	IP        string `json:"ip" desc:"IP数量，如 1 IPv4 + IPv6"`
+	Location  string `json:"location" desc:"服务器位置，多个用逗号分隔"`
+	Remark    string `json:"remark" desc:"其他重要信息，中文"`
 }
 
 // Message 表示OpenAI API的消息格式
@@ -32,9 +28,56 @@ type Message struct {
 
 // ChatRequest 表示OpenAI聊天请求
 type ChatRequest struct {
-	Model     string    `json:"model"`
-	Messages  []Message `json:"messages"`
-	MaxTokens int       `json:"max_tokens,omitempty"`
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	ToolChoice     *ToolChoice     `json:"tool_choice,omitempty"`
+}
+
+// ResponseFormat 指定模型的输出格式
+type ResponseFormat struct {
+	Type       string      `json:"type"` // json_schema 或 json_object
+	JSONSchema *SchemaSpec `json:"json_schema,omitempty"`
+}
+
+// SchemaSpec 结构化输出使用的schema
+type SchemaSpec struct {
+	Name   string      `json:"name"`
+	Strict bool        `json:"strict"`
+	Schema *JSONSchema `json:"schema"`
+}
+
+// Tool 可供模型调用的函数
+type Tool struct {
+	Type     string       `json:"type"` // 固定为 function
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction 函数的名称、说明和参数schema
+type ToolFunction struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  *JSONSchema `json:"parameters"`
+}
+
+// ToolChoice 指定模型必须调用的函数
+type ToolChoice struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
+// ToolCall 模型返回的函数调用
+type ToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
 }
 
 // ChatResponse 表示OpenAI聊天响应
@@ -45,25 +88,23 @@ type ChatResponse struct {
 	Choices []struct {
 		Index   int `json:"index"`
 		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string     `json:"role"`
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
 }
 
-// ParseVPSDescription 使用AI分析VPS描述，提取配置信息
-func ParseVPSDescription(description string) (*VPSConfig, error) {
-	// 检查API配置
-	if config.AIAPIKey == "" {
-		return nil, fmt.Errorf("未配置AI API密钥")
-	}
+// vpsSchema VPS配置的结构化输出schema
+var vpsSchema = SchemaFor(VPSConfig{})
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	// 准备对AI的提示
-	systemPrompt := `你是一个专门提取VPS配置信息的AI助手。请分析提供的VPS描述文本，提取以下信息：
+// vpsSystemPrompt 提取VPS配置的提示词
+const vpsSystemPrompt = `你是一个专门提取VPS配置信息的AI助手。请分析提供的VPS描述文本，提取以下信息：
 CPU核心数、内存大小、硬盘容量及类型、带宽/流量、IP地址信息、服务器位置和其他需要注意的备注事项。
 请以JSON格式返回结果，包含以下字段：cpu, ram, disk, bandwidth, ip, location, remark。
 对于无法确定的字段，请使用空字符串。
@@ -101,219 +142,190 @@ CPU核心数、内存大小、硬盘容量及类型、带宽/流量、IP地址
 请将所有不属于CPU、内存、硬盘、带宽、IP、位置这六个基本字段的重要信息整理到备注(remark)字段中。
 只返回JSON数据，不要有其他文字。`
 
-	// 分析描述
-	userPrompt := fmt.Sprintf("VPS描述: %s", description)
-	log.Printf("AI分析VPS描述: %s", userPrompt)
-
-	// 准备API请求
-	messages := []Message{
-		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: userPrompt},
-	}
-
-	chatReq := ChatRequest{
-		Model:     config.AIModel,
-		Messages:  messages,
-		MaxTokens: 500,
-	}
+// titleSystemPrompt 优化标题的提示词
+const titleSystemPrompt = `你是一个专门优化VPS标题的AI助手。请对输入的VPS标题进行如下优化：
+1. 如果标题中包含英文描述，尝试将其汉化为更易于中文用户理解的形式
+2. 如果标题过长（超过30个字符），进行适当缩短，但保留关键信息
+3. 保留原标题中的规格信息，如CPU核心数、内存大小、硬盘容量等
+4. 保留原标题中的特殊优惠或促销信息
+5. 保留品牌名称，不要汉化品牌名
+6. 如果原标题已经简洁且为中文，则无需更改
 
-	jsonData, err := json.Marshal(chatReq)
-	if err != nil {
-		return nil, fmt.Errorf("序列化AI请求失败: %v", err)
-	}
+请直接返回优化后的标题，不要包含任何解释或额外文字。如果标题已经符合要求或无法优化，则返回原标题。`
 
-	// 创建API请求
-	endpoint := fmt.Sprintf("%s/chat/completions", strings.TrimSuffix(config.AIURL, "/"))
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("创建AI请求失败: %v", err)
-	}
+// ParseVPSDescription 使用AI分析VPS描述，提取配置信息
+func ParseVPSDescription(description string) (*VPSConfig, error) {
+	return ParseVPSDescriptionContext(context.Background(), description)
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", config.AIAPIKey))
+// ParseVPSDescriptionContext 同ParseVPSDescription，ctx取消或超时时中止请求和重试等待
+func ParseVPSDescriptionContext(ctx context.Context, description string) (*VPSConfig, error) {
+	return defaultClient().ParseVPSDescription(ctx, description)
+}
 
-	// 发送请求
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("AI请求失败: %v", err)
+// ParseVPSDescription 使用该客户端分析VPS描述
+func (c *Client) ParseVPSDescription(ctx context.Context, description string) (*VPSConfig, error) {
+	key := cacheKey(c.Model, vpsPromptVersion, description)
+	if cached, ok := c.cacheGet(ctx, key); ok {
+		var config VPSConfig
+		if err := json.Unmarshal(cached, &config); err == nil {
+			return &config, nil
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("AI服务返回非成功状态码: %d", resp.StatusCode)
-	}
+	// 分析描述
+	userPrompt := fmt.Sprintf("VPS描述: %s", description)
+	log.Printf("AI分析VPS描述: %s", userPrompt)
 
-	body, err := io.ReadAll(resp.Body)
+	data, err := c.StructuredChat(ctx, []Message{
+		{Role: "system", Content: vpsSystemPrompt},
+		{Role: "user", Content: userPrompt},
+	}, "vps_config", vpsSchema, 500)
 	if err != nil {
-		return nil, fmt.Errorf("读取AI响应失败: %v", err)
-	}
-
-	// 解析响应
-	var chatResp ChatResponse
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return nil, fmt.Errorf("解析AI响应失败: %v", err)
-	}
-
-	if len(chatResp.Choices) == 0 {
-		return nil, fmt.Errorf("AI响应中没有选择项")
+		return nil, err
 	}
 
-	// 提取AI返回的JSON
-	content := chatResp.Choices[0].Message.Content
-	// 清理可能的markdown格式
-	content = cleanJSONFromMarkdown(content)
-
 	var config VPSConfig
-	if err := json.Unmarshal([]byte(content), &config); err != nil {
+	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("解析AI返回的JSON失败: %v", err)
 	}
+	c.cacheSet(ctx, CacheKindVPS, key, data)
 
 	return &config, nil
 }
 
-// cleanJSONFromMarkdown 从可能包含markdown格式的内容中提取JSON
-func cleanJSONFromMarkdown(content string) string {
-	// 处理可能的代码块
-	if strings.Contains(content, "```json") && strings.Contains(content, "```") {
-		parts := strings.Split(content, "```")
-		for i, part := range parts {
-			if strings.HasPrefix(part, "json") || i > 0 && i < len(parts)-1 {
-				return strings.TrimPrefix(part, "json")
-			}
-		}
-	}
-
-	// 如果没有markdown格式但内容以{开始以}结束，直接返回
-	content = strings.TrimSpace(content)
-	if strings.HasPrefix(content, "{") && strings.HasSuffix(content, "}") {
-		return content
-	}
-
-	return content
-}
-
 // OptimizeTitle 使用AI优化VPS标题，进行汉化和长度优化
 func OptimizeTitle(title string) (string, error) {
-	// 检查API配置
-	if config.AIAPIKey == "" {
-		return title, fmt.Errorf("未配置AI API密钥")
-	}
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	return OptimizeTitleContext(context.Background(), title)
+}
 
-	// 准备对AI的提示
-	systemPrompt := `你是一个专门优化VPS标题的AI助手。请对输入的VPS标题进行如下优化：
-1. 如果标题中包含英文描述，尝试将其汉化为更易于中文用户理解的形式
-2. 如果标题过长（超过30个字符），进行适当缩短，但保留关键信息
-3. 保留原标题中的规格信息，如CPU核心数、内存大小、硬盘容量等
-4. 保留原标题中的特殊优惠或促销信息
-5. 保留品牌名称，不要汉化品牌名
-6. 如果原标题已经简洁且为中文，则无需更改
+// OptimizeTitleContext 同OptimizeTitle，ctx取消或超时时中止请求和重试等待
+func OptimizeTitleContext(ctx context.Context, title string) (string, error) {
+	return defaultClient().OptimizeTitle(ctx, title)
+}
 
-请直接返回优化后的标题，不要包含任何解释或额外文字。如果标题已经符合要求或无法优化，则返回原标题。`
+// OptimizeTitle 使用AI优化VPS标题，失败时返回原标题和错误
+func (c *Client) OptimizeTitle(ctx context.Context, title string) (string, error) {
+	key := cacheKey(c.Model, titlePromptVersion, title)
+	if cached, ok := c.cacheGet(ctx, key); ok {
+		return string(cached), nil
+	}
 
 	// 分析标题
 	userPrompt := fmt.Sprintf("原标题: %s", title)
 
-	// 准备API请求
-	messages := []Message{
-		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: userPrompt},
-	}
-
-	chatReq := ChatRequest{
-		Model:     config.AIModel,
-		Messages:  messages,
+	chatResp, err := c.Chat(ctx, &ChatRequest{
+		Messages: []Message{
+			{Role: "system", Content: titleSystemPrompt},
+			{Role: "user", Content: userPrompt},
+		},
 		MaxTokens: 100,
-	}
-
-	jsonData, err := json.Marshal(chatReq)
-	if err != nil {
-		return title, fmt.Errorf("序列化AI请求失败: %v", err)
-	}
-
-	// 创建API请求
-	endpoint := fmt.Sprintf("%s/chat/completions", strings.TrimSuffix(config.AIURL, "/"))
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonData))
+	})
 	if err != nil {
-		return title, fmt.Errorf("创建AI请求失败: %v", err)
+		return title, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", config.AIAPIKey))
+	// 提取AI返回的优化标题
+	optimizedTitle := strings.TrimSpace(chatResp.Choices[0].Message.Content)
 
-	// 发送请求
-	resp, err := client.Do(req)
-	if err != nil {
-		return title, fmt.Errorf("AI请求失败: %v", err)
+	// AI返回空标题时使用原标题
+	if optimizedTitle == "" {
+		optimizedTitle = title
 	}
-	defer resp.Body.Close()
+	c.cacheSet(ctx, CacheKindTitle, key, []byte(optimizedTitle))
 
-	if resp.StatusCode != http.StatusOK {
-		return title, fmt.Errorf("AI服务返回非成功状态码: %d", resp.StatusCode)
-	}
+	return optimizedTitle, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return title, fmt.Errorf("读取AI响应失败: %v", err)
-	}
+// TitleResult 批量优化标题中单个标题的结果
+type TitleResult struct {
+	Index int    // 在输入中的位置
+	Title string // 优化后的标题，失败时为原标题
+	Err   error  // 失败原因
+}
 
-	// 解析响应
-	var chatResp ChatResponse
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return title, fmt.Errorf("解析AI响应失败: %v", err)
-	}
+// OptimizeTitles 批量优化标题，按客户端的并发数和限流器并发请求，结果与输入顺序一致
+//
+// ctx取消后未开始的标题不再请求，其结果的Err为ctx的错误。
+func (c *Client) OptimizeTitles(ctx context.Context, titles []string) []TitleResult {
+	return c.optimizeTitles(ctx, titles, nil)
+}
 
-	if len(chatResp.Choices) == 0 {
-		return title, fmt.Errorf("AI响应中没有选择项")
+// optimizeTitles 批量优化标题，每个标题完成后在工作协程中调用done，done可能被并发调用
+func (c *Client) optimizeTitles(ctx context.Context, titles []string, done func(TitleResult)) []TitleResult {
+	results := make([]TitleResult, len(titles))
+	for i, title := range titles {
+		results[i] = TitleResult{Index: i, Title: title}
 	}
 
-	// 提取AI返回的优化标题
-	optimizedTitle := strings.TrimSpace(chatResp.Choices[0].Message.Content)
-
-	// 如果AI返回空标题或与原标题相同，使用原标题
-	if optimizedTitle == "" || optimizedTitle == title {
-		return title, nil
+	workers := c.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(titles) {
+		workers = len(titles)
 	}
 
-	return optimizedTitle, nil
-}
-
-// OptimizeTitleAsync 异步优化多个VPS标题
-func OptimizeTitleAsync(titles []string, callback func(int, string)) {
-	// 创建工作池
-	const maxWorkers = 300
-	workChan := make(chan int, len(titles))
+	workChan := make(chan int)
 	var wg sync.WaitGroup
-
-	// 启动工作协程
-	for i := 0; i < maxWorkers; i++ {
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for idx := range workChan {
-				optimizedTitle, err := OptimizeTitle(titles[idx])
-				if err != nil {
-					log.Printf("优化标题 %s 失败: %v", titles[idx], err)
-					continue
-				}
-
-				// 回调函数处理优化后的标题
-				if callback != nil {
-					callback(idx, optimizedTitle)
+				// 每个下标只由一个协程写入，无需加锁
+				results[idx].Title, results[idx].Err = c.OptimizeTitle(ctx, titles[idx])
+				if done != nil {
+					done(results[idx])
 				}
 			}
 		}()
 	}
 
-	// 发送工作
-	for i := range titles {
-		workChan <- i
+	next := 0
+dispatch:
+	for next < len(titles) {
+		select {
+		case workChan <- next:
+			next++
+		case <-ctx.Done():
+			break dispatch
+		}
 	}
 	close(workChan)
-
-	// 等待所有工作完成
 	wg.Wait()
+
+	for ; next < len(titles); next++ {
+		results[next].Err = ctx.Err()
+	}
+	return results
+}
+
+// OptimizeTitles 使用默认客户端批量优化标题，并发数和限流由配置决定
+func OptimizeTitles(ctx context.Context, titles []string) []TitleResult {
+	return defaultClient().OptimizeTitles(ctx, titles)
+}
+
+// OptimizeTitleAsync 批量优化VPS标题，每个标题优化成功后立即调用callback
+//
+// callback在工作协程中被并发调用，调用顺序与输入顺序无关。
+//
+// Deprecated: 使用 OptimizeTitles 获取包含错误的结果。
+func OptimizeTitleAsync(titles []string, callback func(int, string)) {
+	OptimizeTitleAsyncContext(context.Background(), titles, callback)
+}
+
+// OptimizeTitleAsyncContext 同OptimizeTitleAsync，ctx取消后不再处理剩余的标题
+//
+// Deprecated: 使用 OptimizeTitles 获取包含错误的结果。
+func OptimizeTitleAsyncContext(ctx context.Context, titles []string, callback func(int, string)) {
+	defaultClient().optimizeTitles(ctx, titles, func(result TitleResult) {
+		if result.Err != nil {
+			log.Printf("优化标题 %s 失败: %v", titles[result.Index], result.Err)
+			return
+		}
+		if callback != nil {
+			callback(result.Index, result.Title)
+		}
+	})
 }
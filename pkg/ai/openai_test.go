@@ -0,0 +1,307 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeChat 模拟OpenAI兼容的聊天接口，记录并发数和请求到达时间
+type fakeChat struct {
+	*httptest.Server
+
+	// reply 根据用户消息返回内容，返回错误时以400响应
+	reply func(req *ChatRequest) (string, error)
+	// delay 根据用户消息决定处理耗时
+	delay func(req *ChatRequest) time.Duration
+
+	inFlight    int32
+	maxInFlight int32
+
+	mu       sync.Mutex
+	arrivals []time.Time
+	requests []*ChatRequest
+}
+
+func newFakeChat(t *testing.T) *fakeChat {
+	t.Helper()
+	f := &fakeChat{
+		reply: func(req *ChatRequest) (string, error) { return "", nil },
+		delay: func(req *ChatRequest) time.Duration { return 0 },
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&f.inFlight, 1)
+		defer atomic.AddInt32(&f.inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&f.maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&f.maxInFlight, max, n) {
+				break
+			}
+		}
+
+		var req ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.arrivals = append(f.arrivals, time.Now())
+		f.requests = append(f.requests, &req)
+		f.mu.Unlock()
+
+		time.Sleep(f.delay(&req))
+
+		content, err := f.reply(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": map[string]string{"message": err.Error(), "type": "invalid_request_error"},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{
+				"index":   0,
+				"message": map[string]string{"role": "assistant", "content": content},
+			}},
+			"usage": map[string]int{"total_tokens": 10},
+		})
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// client 创建指向假接口的客户端，重试等待缩短以加快测试
+func (f *fakeChat) client() *Client {
+	c := NewClient(f.URL, "test-key", "test-model")
+	c.BaseDelay = time.Millisecond
+	c.MaxDelay = 10 * time.Millisecond
+	return c
+}
+
+// requestCount 已收到的请求数
+func (f *fakeChat) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// lastUserMessage 请求中最后一条用户消息
+func lastUserMessage(req *ChatRequest) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			return req.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// titleOf 从标题优化请求中取出原标题
+func titleOf(req *ChatRequest) string {
+	return strings.TrimPrefix(lastUserMessage(req), "原标题: ")
+}
+
+func TestOptimizeTitlesConcurrencyAndOrder(t *testing.T) {
+	f := newFakeChat(t)
+	titles := make([]string, 12)
+	for i := range titles {
+		titles[i] = fmt.Sprintf("title-%02d", i)
+	}
+	// 越靠前的标题处理越慢，完成顺序与输入顺序相反
+	f.delay = func(req *ChatRequest) time.Duration {
+		var i int
+		fmt.Sscanf(titleOf(req), "title-%d", &i)
+		return time.Duration(len(titles)-i) * 5 * time.Millisecond
+	}
+	f.reply = func(req *ChatRequest) (string, error) {
+		title := titleOf(req)
+		if title == "title-05" {
+			return "", fmt.Errorf("bad title")
+		}
+		if title == "title-07" {
+			return "  ", nil
+		}
+		return "优化 " + title, nil
+	}
+
+	c := f.client()
+	c.Concurrency = 3
+	results := c.OptimizeTitles(context.Background(), titles)
+
+	if got := atomic.LoadInt32(&f.maxInFlight); got != 3 {
+		t.Errorf("max in-flight requests = %d, want 3", got)
+	}
+	if len(results) != len(titles) {
+		t.Fatalf("results = %d, want %d", len(results), len(titles))
+	}
+	for i, r := range results {
+		if r.Index != i {
+			t.Errorf("results[%d].Index = %d", i, r.Index)
+		}
+		switch i {
+		case 5:
+			// 失败时返回原标题和错误
+			if r.Err == nil || r.Title != titles[i] {
+				t.Errorf("results[5] = %+v, want original title and error", r)
+			}
+		case 7:
+			// 返回空白时使用原标题
+			if r.Err != nil || r.Title != titles[i] {
+				t.Errorf("results[7] = %+v, want original title", r)
+			}
+		default:
+			if r.Err != nil || r.Title != "优化 "+titles[i] {
+				t.Errorf("results[%d] = %+v, want %q", i, r, "优化 "+titles[i])
+			}
+		}
+	}
+}
+
+func TestOptimizeTitlesConcurrencyBounds(t *testing.T) {
+	tests := []struct {
+		concurrency int
+		titles      int
+		want        int32
+	}{
+		{0, 4, 1},  // 未设置并发数时串行
+		{1, 4, 1},  // 串行
+		{8, 3, 3},  // 并发数不超过标题数
+		{4, 10, 4}, // 按并发数限制
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%d", tt.concurrency, tt.titles), func(t *testing.T) {
+			f := newFakeChat(t)
+			f.delay = func(*ChatRequest) time.Duration { return 20 * time.Millisecond }
+			f.reply = func(req *ChatRequest) (string, error) { return titleOf(req), nil }
+
+			c := f.client()
+			c.Concurrency = tt.concurrency
+			titles := make([]string, tt.titles)
+			for i := range titles {
+				titles[i] = fmt.Sprint(i)
+			}
+			c.OptimizeTitles(context.Background(), titles)
+
+			if got := atomic.LoadInt32(&f.maxInFlight); got != tt.want {
+				t.Errorf("max in-flight requests = %d, want %d", got, tt.want)
+			}
+			if n := f.requestCount(); n != tt.titles {
+				t.Errorf("requests = %d, want %d", n, tt.titles)
+			}
+		})
+	}
+}
+
+func TestOptimizeTitlesCancel(t *testing.T) {
+	f := newFakeChat(t)
+	f.delay = func(*ChatRequest) time.Duration { return 30 * time.Millisecond }
+	f.reply = func(req *ChatRequest) (string, error) { return "ok", nil }
+
+	c := f.client()
+	c.Concurrency = 2
+	titles := make([]string, 20)
+	for i := range titles {
+		titles[i] = fmt.Sprint(i)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	results := c.OptimizeTitles(ctx, titles)
+
+	// 取消后未开始的标题不再请求，结果仍按输入顺序返回
+	if requested := f.requestCount(); requested >= len(titles) {
+		t.Errorf("requests = %d, want fewer than %d after cancel", requested, len(titles))
+	}
+	if !errors.Is(results[len(results)-1].Err, context.DeadlineExceeded) {
+		t.Errorf("last result error = %v, want context error", results[len(results)-1].Err)
+	}
+	for i, r := range results {
+		if r.Index != i {
+			t.Errorf("results[%d].Index = %d", i, r.Index)
+		}
+		if r.Err != nil && r.Title != titles[i] {
+			t.Errorf("results[%d] = %+v, want original title on error", i, r)
+		}
+	}
+}
+
+func TestOptimizeTitlesCallsDonePerTitle(t *testing.T) {
+	f := newFakeChat(t)
+	// 第一个标题很慢，其他标题的回调不应等待它完成
+	f.delay = func(req *ChatRequest) time.Duration {
+		if titleOf(req) == "slow" {
+			return 200 * time.Millisecond
+		}
+		return 0
+	}
+	f.reply = func(req *ChatRequest) (string, error) { return "优化 " + titleOf(req), nil }
+
+	c := f.client()
+	c.Concurrency = 2
+	var mu sync.Mutex
+	var order []int
+	titles := []string{"slow", "fast-1", "fast-2"}
+	results := c.optimizeTitles(context.Background(), titles, func(r TitleResult) {
+		if r.Err != nil || r.Title != "优化 "+titles[r.Index] {
+			t.Errorf("done(%+v)", r)
+		}
+		mu.Lock()
+		order = append(order, r.Index)
+		mu.Unlock()
+	})
+
+	if len(results) != 3 {
+		t.Fatalf("results = %d, want 3", len(results))
+	}
+	if len(order) != 3 || order[2] != 0 {
+		t.Errorf("done order = %v, want the slow title last", order)
+	}
+}
+
+func TestOptimizeTitleUsesCache(t *testing.T) {
+	f := newFakeChat(t)
+	f.reply = func(req *ChatRequest) (string, error) { return "优化 " + titleOf(req), nil }
+
+	c := f.client()
+	c.Cache = newMemoryCache()
+	for i := 0; i < 3; i++ {
+		got, err := c.OptimizeTitle(context.Background(), "a")
+		if err != nil || got != "优化 a" {
+			t.Fatalf("OptimizeTitle() = %q, %v", got, err)
+		}
+	}
+	if n := f.requestCount(); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+}
+
+// memoryCache 测试使用的内存缓存
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: map[string][]byte{}}
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.entries[key]
+	return v, ok
+}
+
+func (m *memoryCache) Set(ctx context.Context, entry *CacheEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[entry.Key] = entry.Value
+}
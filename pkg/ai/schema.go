@@ -0,0 +1,136 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// JSONSchema JSON Schema的子集，足以描述由字符串、数字和布尔字段组成的扁平对象
+type JSONSchema struct {
+	Type                 string                 `json:"type"`
+	Description          string                 `json:"description,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+}
+
+// SchemaFor 根据结构体的json标签生成schema，desc标签作为字段说明
+//
+// 所有字段都是必填的，且不允许额外字段，以满足严格模式的要求。
+func SchemaFor(v any) *JSONSchema {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	noExtra := false
+	schema := &JSONSchema{
+		Type:                 "object",
+		Properties:           make(map[string]*JSONSchema),
+		AdditionalProperties: &noExtra,
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = &JSONSchema{
+			Type:        schemaType(field.Type.Kind()),
+			Description: field.Tag.Get("desc"),
+		}
+		schema.Required = append(schema.Required, name)
+	}
+	return schema
+}
+
+// schemaType Go类型对应的schema类型
+func schemaType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// Validate 校验JSON对象是否符合schema，返回所有不符合的地方
+func (s *JSONSchema) Validate(data []byte) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		return fmt.Errorf("不是JSON对象")
+	}
+
+	var problems []string
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			problems = append(problems, fmt.Sprintf("缺少字段 %s", name))
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				problems = append(problems, fmt.Sprintf("不允许的字段 %s", name))
+			}
+			continue
+		}
+		if !matchesType(object[name], prop.Type) {
+			problems = append(problems, fmt.Sprintf("字段 %s 应为 %s", name, prop.Type))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "；"))
+	}
+	return nil
+}
+
+// matchesType 判断JSON值是否为指定的schema类型
+func matchesType(raw json.RawMessage, typ string) bool {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return false
+	}
+	switch typ {
+	case "string":
+		return raw[0] == '"'
+	case "boolean":
+		return string(raw) == "true" || string(raw) == "false"
+	case "integer":
+		if raw[0] == '"' {
+			return false
+		}
+		var n json.Number
+		if json.Unmarshal(raw, &n) != nil {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	case "number":
+		var n float64
+		return raw[0] != '"' && json.Unmarshal(raw, &n) == nil
+	case "object":
+		return raw[0] == '{'
+	default:
+		return true
+	}
+}
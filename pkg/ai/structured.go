@@ -0,0 +1,157 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// 结构化输出的方式，服务不支持时按顺序降级
+const (
+	outputJSONSchema int32 = iota // response_format 的 json_schema
+	outputTool                    // 强制调用函数，参数即输出
+	outputPrompt                  // 仅通过提示词要求返回JSON
+)
+
+// outputModeNames 结构化输出方式的名称，用于日志
+var outputModeNames = map[int32]string{
+	outputJSONSchema: "json_schema",
+	outputTool:       "函数调用",
+	outputPrompt:     "提示词",
+}
+
+// outputParams 各结构化输出方式使用的请求参数，服务的错误信息提到这些参数时才降级
+var outputParams = map[int32][]string{
+	outputJSONSchema: {"response_format", "json_schema"},
+	outputTool:       {"tools", "tool_choice"},
+}
+
+// fencePattern markdown代码块
+var fencePattern = regexp.MustCompile("(?s)```[a-zA-Z]*\\s*(.*?)```")
+
+// StructuredChat 请求模型返回符合schema的JSON对象，返回校验通过的JSON
+//
+// 优先使用response_format的json_schema，服务不支持时依次降级为函数调用和提示词，
+// 只有错误信息明确指出不支持相应参数时才降级，降级后的方式请求成功后客户端才记住它。
+// 输出不符合schema时将错误反馈给模型，再重试一次。
+func (c *Client) StructuredChat(ctx context.Context, messages []Message, name string, schema *JSONSchema, maxTokens int) ([]byte, error) {
+	initial := c.outputMode.Load()
+	mode := initial
+	content, err := c.structuredOnce(ctx, mode, messages, name, schema, maxTokens)
+	for err != nil && mode < outputPrompt && unsupportedOutput(err, mode) {
+		mode++
+		content, err = c.structuredOnce(ctx, mode, messages, name, schema, maxTokens)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 其他请求可能已同时修改了方式，只在未被修改时记录
+	if mode != initial && c.outputMode.CompareAndSwap(initial, mode) {
+		log.Printf("AI服务不支持%s，结构化输出改用%s", outputModeNames[initial], outputModeNames[mode])
+	}
+
+	data := cleanJSONFromMarkdown(content)
+	invalid := schema.Validate([]byte(data))
+	if invalid == nil {
+		return []byte(data), nil
+	}
+
+	// 修复重试：附上上次的输出和错误，要求模型重新输出
+	repair := append(append([]Message(nil), messages...),
+		Message{Role: "assistant", Content: content},
+		Message{Role: "user", Content: fmt.Sprintf("上面的输出不符合要求：%v。请只返回一个JSON对象，包含且仅包含字段 %s，值的类型与要求一致，不要有其他文字。",
+			invalid, strings.Join(schema.Required, ", "))},
+	)
+	content, err = c.structuredOnce(ctx, mode, repair, name, schema, maxTokens)
+	if err != nil {
+		return nil, err
+	}
+	data = cleanJSONFromMarkdown(content)
+	if err := schema.Validate([]byte(data)); err != nil {
+		return nil, fmt.Errorf("AI返回的JSON不符合要求: %v", err)
+	}
+	return []byte(data), nil
+}
+
+// structuredOnce 按指定方式发送一次请求，返回模型输出的原始内容
+func (c *Client) structuredOnce(ctx context.Context, mode int32, messages []Message, name string, schema *JSONSchema, maxTokens int) (string, error) {
+	chatReq := &ChatRequest{Messages: messages, MaxTokens: maxTokens}
+	switch mode {
+	case outputJSONSchema:
+		chatReq.ResponseFormat = &ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &SchemaSpec{Name: name, Strict: true, Schema: schema},
+		}
+	case outputTool:
+		chatReq.Tools = []Tool{{
+			Type:     "function",
+			Function: ToolFunction{Name: name, Description: "返回提取的结果", Parameters: schema},
+		}}
+		chatReq.ToolChoice = &ToolChoice{Type: "function"}
+		chatReq.ToolChoice.Function.Name = name
+	}
+
+	chatResp, err := c.Chat(ctx, chatReq)
+	if err != nil {
+		return "", err
+	}
+
+	message := chatResp.Choices[0].Message
+	if mode == outputTool {
+		for _, call := range message.ToolCalls {
+			if call.Function.Name == name {
+				return call.Function.Arguments, nil
+			}
+		}
+	}
+	return message.Content, nil
+}
+
+// unsupportedOutput 判断错误是否表示服务不支持指定的结构化输出方式
+//
+// 请求被拒绝且错误信息提到该方式使用的参数时才认为不支持，
+// 其他原因的失败（如上下文过长、内容审核）不应导致降级。
+func unsupportedOutput(err error, mode int32) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity:
+	default:
+		return false
+	}
+	message := strings.ToLower(apiErr.Message)
+	for _, param := range outputParams[mode] {
+		if strings.Contains(message, param) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanJSONFromMarkdown 从可能包含markdown格式或说明文字的内容中提取JSON对象
+func cleanJSONFromMarkdown(content string) string {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "{") && strings.HasSuffix(content, "}") {
+		return content
+	}
+
+	// 取第一个内容为JSON对象的代码块，跳过前后的说明文字和其他代码块
+	for _, match := range fencePattern.FindAllStringSubmatch(content, -1) {
+		if block := strings.TrimSpace(match[1]); strings.HasPrefix(block, "{") {
+			return block
+		}
+	}
+
+	// 没有代码块时截取第一个 { 到最后一个 } 之间的内容
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start >= 0 && end > start {
+		return content[start : end+1]
+	}
+	return content
+}
@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+type testOutput struct {
+	Name string `json:"name"`
+}
+
+var testSchema = SchemaFor(testOutput{})
+
+// requestMode 根据请求参数判断使用的结构化输出方式
+func requestMode(req *ChatRequest) int32 {
+	switch {
+	case req.ResponseFormat != nil:
+		return outputJSONSchema
+	case len(req.Tools) > 0:
+		return outputTool
+	}
+	return outputPrompt
+}
+
+func structuredChat(c *Client) (string, error) {
+	data, err := c.StructuredChat(context.Background(), []Message{{Role: "user", Content: "test"}}, "test", testSchema, 100)
+	if err != nil {
+		return "", err
+	}
+	var out testOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return "", err
+	}
+	return out.Name, nil
+}
+
+func TestStructuredChatFallsBackOnUnsupportedParams(t *testing.T) {
+	f := newFakeChat(t)
+	f.reply = func(req *ChatRequest) (string, error) {
+		switch requestMode(req) {
+		case outputJSONSchema:
+			return "", fmt.Errorf("Invalid parameter: 'response_format' of type 'json_schema' is not supported with this model.")
+		case outputTool:
+			return "", fmt.Errorf("This model does not support tools.")
+		}
+		return "```json\n{\"name\": \"ok\"}\n```", nil
+	}
+
+	c := f.client()
+	if got, err := structuredChat(c); err != nil || got != "ok" {
+		t.Fatalf("StructuredChat() = %q, %v", got, err)
+	}
+	if n := f.requestCount(); n != 3 {
+		t.Errorf("requests = %d, want 3", n)
+	}
+	if mode := c.outputMode.Load(); mode != outputPrompt {
+		t.Errorf("output mode = %s, want %s", outputModeNames[mode], outputModeNames[outputPrompt])
+	}
+
+	// 之后直接使用可用的方式
+	if _, err := structuredChat(c); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.requestCount(); n != 4 {
+		t.Errorf("requests = %d, want 4", n)
+	}
+}
+
+func TestStructuredChatKeepsModeOnUnrelatedError(t *testing.T) {
+	f := newFakeChat(t)
+	failures := 1
+	f.reply = func(req *ChatRequest) (string, error) {
+		if requestMode(req) != outputJSONSchema {
+			t.Errorf("unexpected fallback to %s", outputModeNames[requestMode(req)])
+		}
+		if failures > 0 {
+			failures--
+			return "", fmt.Errorf("This model's maximum context length is 4096 tokens.")
+		}
+		return `{"name": "ok"}`, nil
+	}
+
+	c := f.client()
+	if _, err := structuredChat(c); err == nil {
+		t.Fatal("StructuredChat() error = nil, want context length error")
+	}
+	if n := f.requestCount(); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+	if mode := c.outputMode.Load(); mode != outputJSONSchema {
+		t.Errorf("output mode = %s, want json_schema", outputModeNames[mode])
+	}
+
+	if got, err := structuredChat(c); err != nil || got != "ok" {
+		t.Fatalf("StructuredChat() = %q, %v", got, err)
+	}
+}
+
+func TestStructuredChatKeepsModeWhenFallbackFails(t *testing.T) {
+	f := newFakeChat(t)
+	f.reply = func(req *ChatRequest) (string, error) {
+		if requestMode(req) == outputJSONSchema {
+			return "", fmt.Errorf("response_format is not supported")
+		}
+		return "", fmt.Errorf("content rejected by moderation")
+	}
+
+	// 降级后的方式也失败时不记录降级
+	c := f.client()
+	if _, err := structuredChat(c); err == nil {
+		t.Fatal("StructuredChat() error = nil, want error")
+	}
+	if n := f.requestCount(); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+	if mode := c.outputMode.Load(); mode != outputJSONSchema {
+		t.Errorf("output mode = %s, want json_schema", outputModeNames[mode])
+	}
+}
+
+func TestUnsupportedOutput(t *testing.T) {
+	tests := []struct {
+		err  error
+		mode int32
+		want bool
+	}{
+		{&APIError{StatusCode: 400, Message: "Unknown parameter: 'response_format'."}, outputJSONSchema, true},
+		{&APIError{StatusCode: 422, Message: "json_schema is not supported"}, outputJSONSchema, true},
+		{&APIError{StatusCode: 400, Message: "'tools' is not supported"}, outputJSONSchema, false},
+		{&APIError{StatusCode: 400, Message: "'tools' is not supported"}, outputTool, true},
+		{&APIError{StatusCode: 400, Message: "tool_choice must be auto"}, outputTool, true},
+		{&APIError{StatusCode: 400, Message: "maximum context length exceeded"}, outputJSONSchema, false},
+		{&APIError{StatusCode: 401, Message: "invalid response_format key"}, outputJSONSchema, false},
+		{&APIError{StatusCode: 500, Message: "response_format failed"}, outputJSONSchema, false},
+		{fmt.Errorf("response_format"), outputJSONSchema, false},
+	}
+	for _, tt := range tests {
+		if got := unsupportedOutput(tt.err, tt.mode); got != tt.want {
+			t.Errorf("unsupportedOutput(%v, %s) = %v, want %v", tt.err, outputModeNames[tt.mode], got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package currency 解析汇率文件
+package currency
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ECBBase 欧洲央行汇率的基准货币
+const ECBBase = "EUR"
+
+// codePattern 货币代码，三位大写字母
+var codePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ValidCode 判断是否为有效的货币代码
+func ValidCode(code string) bool {
+	return codePattern.MatchString(code)
+}
+
+// ParseCSV 解析 currency,rate 格式的CSV，rate为1单位基准货币可兑换的该货币数量
+//
+// 第一行的汇率不是数字时视为表头跳过，空行和多余的列被忽略。
+func ParseCSV(r io.Reader) (map[string]float64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	rates := make(map[string]float64)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第%d行格式错误: %w", line, err)
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("第%d行缺少汇率", line)
+		}
+
+		code := strings.ToUpper(strings.TrimSpace(record[0]))
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("第%d行汇率无效: %s", line, record[1])
+		}
+		if !ValidCode(code) {
+			return nil, fmt.Errorf("第%d行货币代码无效: %s", line, record[0])
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("第%d行汇率必须大于0", line)
+		}
+		rates[code] = rate
+	}
+	if len(rates) == 0 {
+		return nil, errors.New("文件中没有汇率")
+	}
+	return rates, nil
+}
+
+// ecbEnvelope 欧洲央行 eurofxref XML 的结构，外层Cube下每个日期一个Cube
+type ecbEnvelope struct {
+	Cube struct {
+		Days []struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string `xml:"currency,attr"`
+				Rate     string `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// ParseECB 解析欧洲央行的 eurofxref XML，返回以欧元为基准的汇率（含EUR本身）和汇率日期
+//
+// 历史文件包含多个日期，只取第一个，即最新的汇率。
+func ParseECB(r io.Reader) (map[string]float64, string, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, "", fmt.Errorf("解析XML失败: %w", err)
+	}
+	if len(envelope.Cube.Days) == 0 {
+		return nil, "", errors.New("文件中没有汇率")
+	}
+
+	day := envelope.Cube.Days[0]
+	rates := map[string]float64{ECBBase: 1}
+	for _, item := range day.Rates {
+		code := strings.ToUpper(strings.TrimSpace(item.Currency))
+		rate, err := strconv.ParseFloat(strings.TrimSpace(item.Rate), 64)
+		if !ValidCode(code) || err != nil || rate <= 0 {
+			return nil, "", fmt.Errorf("汇率无效: %s %s", item.Currency, item.Rate)
+		}
+		rates[code] = rate
+	}
+	if len(rates) == 1 {
+		return nil, "", errors.New("文件中没有汇率")
+	}
+	return rates, day.Time, nil
+}
+
+// Rebase 将汇率换算为以base为基准，base不在汇率中时返回错误
+func Rebase(rates map[string]float64, base string) (map[string]float64, error) {
+	baseRate, ok := rates[base]
+	if !ok || baseRate <= 0 {
+		return nil, fmt.Errorf("汇率中没有基准货币 %s", base)
+	}
+
+	result := make(map[string]float64, len(rates))
+	for code, rate := range rates {
+		result[code] = rate / baseRate
+	}
+	return result, nil
+}
@@ -0,0 +1,207 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// maxCheckBodySize 检查库存时读取的最大页面大小
+	maxCheckBodySize = 1 << 20
+	// maxCheckRedirects 检查库存时最多跟随的重定向次数
+	maxCheckRedirects = 5
+)
+
+// errBlockedAddress 目标地址为内网或本机地址
+var errBlockedAddress = errors.New("不允许访问内网地址")
+
+// blockedPrefixes net/netip未归为内网的保留地址段：本网络（0.0.0.0/8）和运营商级NAT（100.64.0.0/10）
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// checkClient 检查购买页面使用的客户端，只允许连接公网地址
+//
+// 购买链接来自外部来源，在建立连接时检查解析后的实际地址，重定向和DNS重新绑定都无法绕过。
+var checkClient = newCheckClient(blockedAddress)
+
+// Availability 购买页面的检查结果
+type Availability struct {
+	StatusCode int  // HTTP状态码
+	SoldOut    bool // 页面中出现了缺货标记
+}
+
+// CheckAvailability 请求购买页面，检查是否存在以及是否已售罄
+//
+// 页面有多个套餐时，只有所有可购买的套餐都带有缺货标记（不区分大小写）才认为售罄，
+// 避免其中一个套餐缺货导致整个页面被标记。orderMarkers为购买按钮的文字，用于划分套餐。
+func CheckAvailability(ctx context.Context, pageURL string, markers, orderMarkers []string) (*Availability, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("无效的链接: %s", pageURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Accept", "text/html, */*;q=0.8")
+	req.Header.Set("User-Agent", "go-nextjs-feed/1.0")
+
+	resp, err := checkClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	result := &Availability{StatusCode: resp.StatusCode}
+	if resp.StatusCode != http.StatusOK {
+		return result, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCheckBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+	reader, err := charset.NewReader(bytes.NewReader(body), resp.Header.Get("Content-Type"))
+	if err != nil {
+		reader = bytes.NewReader(body)
+	}
+	doc, err := goquery.NewDocumentFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("解析页面失败: %v", err)
+	}
+	result.SoldOut = soldOut(doc, markers, orderMarkers)
+	return result, nil
+}
+
+// soldOut 判断页面是否售罄
+//
+// 页面可见文字中没有缺货标记时未售罄；没有购买按钮时以缺货标记为准；
+// 否则每个购买按钮所在的套餐区域都有缺货标记或按钮被禁用时才算售罄。
+func soldOut(doc *goquery.Document, markers, orderMarkers []string) bool {
+	doc.Find("script, style, noscript, template, [hidden]").Remove()
+	if !containsMarker(doc.Text(), markers) {
+		return false
+	}
+
+	controls := doc.Find("a, button, input[type=submit], input[type=button]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return containsMarker(s.Text()+" "+s.AttrOr("value", ""), orderMarkers)
+	})
+	if controls.Length() == 0 {
+		return true
+	}
+
+	available := false
+	controls.EachWithBreak(func(_ int, control *goquery.Selection) bool {
+		if control.Is("[disabled], [aria-disabled=true], .disabled") {
+			return true
+		}
+		if !containsMarker(planBlock(control, controls).Text(), markers) {
+			available = true
+			return false
+		}
+		return true
+	})
+	return !available
+}
+
+// planBlock 购买按钮所在的套餐区域
+//
+// 从按钮向上查找，直到上层元素包含其他购买按钮，或当前元素是重复排列的套餐之一
+// （如同一class的卡片、表格行、列表项），其他套餐可能没有购买按钮。
+func planBlock(control, controls *goquery.Selection) *goquery.Selection {
+	block := control
+	for parent := control.Parent(); parent.Length() > 0; parent = parent.Parent() {
+		if parent.FindSelection(controls).Length() > 1 || repeated(block) {
+			break
+		}
+		block = parent
+	}
+	return block
+}
+
+// repeated 元素是否与同级元素构成重复的列表
+func repeated(s *goquery.Selection) bool {
+	tag := goquery.NodeName(s)
+	class := strings.TrimSpace(s.AttrOr("class", ""))
+	if class == "" && tag != "tr" && tag != "li" {
+		return false
+	}
+	return s.Siblings().FilterFunction(func(_ int, sibling *goquery.Selection) bool {
+		return goquery.NodeName(sibling) == tag && strings.TrimSpace(sibling.AttrOr("class", "")) == class
+	}).Length() > 0
+}
+
+// containsMarker 文本中是否包含任一标记，不区分大小写
+func containsMarker(text string, markers []string) bool {
+	text = strings.ToLower(text)
+	for _, marker := range markers {
+		if marker != "" && strings.Contains(text, strings.ToLower(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// newCheckClient 创建连接前按blocked检查目标地址的客户端，不使用代理，否则检查的是代理的地址
+func newCheckClient(blocked func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if blocked(addrPort.Addr().Unmap()) {
+				return fmt.Errorf("%w: %s", errBlockedAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConnsPerHost: 2,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxCheckRedirects {
+				return fmt.Errorf("重定向次数过多")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("不支持重定向到 %s", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// blockedAddress 是否为本机、内网、链路本地等非公网地址
+func blockedAddress(addr netip.Addr) bool {
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
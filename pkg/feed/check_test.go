@@ -0,0 +1,148 @@
+package feed
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	testStockMarkers = []string{"out of stock", "sold out", "缺货"}
+	testOrderMarkers = []string{"order", "buy", "购买"}
+)
+
+func TestBlockedAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"127.1.2.3", true},
+		{"10.0.0.1", true},
+		{"172.16.5.4", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true}, // 云服务元数据地址
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"::", true},
+		{"8.8.8.8", false},
+		{"104.16.0.1", false},
+		{"2606:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := blockedAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("blockedAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckAvailabilityRejectsPrivateAddresses(t *testing.T) {
+	var requested atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested.Store(true)
+	}))
+	defer server.Close()
+
+	for _, url := range []string{server.URL, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)} {
+		_, err := CheckAvailability(context.Background(), url, testStockMarkers, testOrderMarkers)
+		if err == nil || !strings.Contains(err.Error(), errBlockedAddress.Error()) {
+			t.Errorf("CheckAvailability(%s) error = %v, want blocked address", url, err)
+		}
+	}
+	if requested.Load() {
+		t.Error("request reached the loopback server")
+	}
+
+	if _, err := CheckAvailability(context.Background(), "file:///etc/passwd", nil, nil); err == nil {
+		t.Error("CheckAvailability(file://) error = nil")
+	}
+}
+
+func TestCheckAvailabilityRejectsRedirectToBlockedAddress(t *testing.T) {
+	// 内网服务监听在127.0.0.2，模拟的公网服务在127.0.0.1，只禁止前者
+	listener, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("无法监听127.0.0.2: %v", err)
+	}
+	internal := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the internal server")
+	}))
+	internal.Listener.Close()
+	internal.Listener = listener
+	internal.Start()
+	defer internal.Close()
+
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, internal.URL+"/admin", http.StatusFound)
+			return
+		}
+		w.Write([]byte("<html><body><a href='/cart'>Order Now</a></body></html>"))
+	}))
+	defer public.Close()
+
+	internalAddr := netip.MustParseAddr("127.0.0.2")
+	original := checkClient
+	checkClient = newCheckClient(func(addr netip.Addr) bool { return addr == internalAddr })
+	defer func() { checkClient = original }()
+
+	result, err := CheckAvailability(context.Background(), public.URL, testStockMarkers, testOrderMarkers)
+	if err != nil || result.StatusCode != http.StatusOK || result.SoldOut {
+		t.Fatalf("CheckAvailability() = %+v, %v", result, err)
+	}
+	_, err = CheckAvailability(context.Background(), public.URL+"/redirect", testStockMarkers, testOrderMarkers)
+	if err == nil || !strings.Contains(err.Error(), errBlockedAddress.Error()) {
+		t.Errorf("CheckAvailability(redirect) error = %v, want blocked address", err)
+	}
+}
+
+func TestSoldOut(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want bool
+	}{
+		{"in stock", `<h1>KVM 1GB</h1><a href="/cart?pid=1">Order Now</a>`, false},
+		{"marker without order button", `<h1>KVM 1GB</h1><p>Out of Stock</p>`, true},
+		{"marker next to the only button", `<div><h1>KVM 1GB</h1><span>Sold Out</span><a href="/cart">Order</a></div>`, true},
+		{"disabled button", `<div><p>缺货</p><button disabled>立即购买</button></div>`, true},
+		{"marker only in script", `<script>var text = "Out of stock";</script><a href="/cart">Order Now</a>`, false},
+		{"hidden marker", `<div hidden>Sold out</div><a href="/cart">Buy</a>`, false},
+		{"one of several plans sold out", `
+			<div class="plan"><h3>Small</h3><span>Out of Stock</span><a href="/cart?pid=1">Order Now</a></div>
+			<div class="plan"><h3>Large</h3><span>$10/mo</span><a href="/cart?pid=2">Order Now</a></div>`, false},
+		{"sold out plan without button", `
+			<div class="plan"><h3>Small</h3><span>Sold Out</span></div>
+			<div class="plan"><h3>Large</h3><a href="/cart?pid=2">Order Now</a></div>`, false},
+		{"sold out list item", `
+			<ul><li>Small 缺货</li><li>Large <a href="/cart?pid=2">立即购买</a></li></ul>`, false},
+		{"all plans sold out", `
+			<table>
+			<tr><td>Small</td><td>Out of stock</td><td><a href="/cart?pid=1">Order</a></td></tr>
+			<tr><td>Large</td><td>Out of stock</td><td><a href="/cart?pid=2">Order</a></td></tr>
+			</table>`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><body>" + tt.html + "</body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := soldOut(doc, testStockMarkers, testOrderMarkers); got != tt.want {
+				t.Errorf("soldOut() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
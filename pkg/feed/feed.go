@@ -0,0 +1,157 @@
+// Package feed 从各类数据来源抓取优惠条目
+package feed
+
+import (
+	"context"
+	"fmt"
+	"go-nextjs/models"
+	"go-nextjs/pkg/ai"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxBodySize 单次抓取允许的最大响应体
+const maxBodySize = 10 << 20
+
+// Item 从来源中抓取到的一条优惠
+type Item struct {
+	ExternalID   string     `json:"external_id"` // 来源中的唯一标识，缺失时使用链接
+	Title        string     `json:"title"`
+	Description  string     `json:"description"` // 原始描述，用于AI提取配置
+	Provider     string     `json:"provider"`
+	Price        float64    `json:"price"`
+	Currency     string     `json:"currency"`
+	BillingCycle string     `json:"billing_cycle"`
+	URL          string     `json:"url"`
+	EndsAt       *time.Time `json:"ends_at"` // 优惠结束时间
+	Enrich       bool       `json:"-"`       // 新条目需要AI从描述中提取配置并优化标题
+	ai.VPSConfig
+}
+
+// Request 抓取参数
+type Request struct {
+	Type         string // 来源类型
+	URL          string // 抓取地址
+	ETag         string // 上次响应的ETag，用于条件请求
+	LastModified string // 上次响应的Last-Modified，用于条件请求
+
+	Selectors models.SelectorRule // HTML来源的选择器规则
+}
+
+// Result 抓取结果
+type Result struct {
+	Items        []Item
+	ETag         string // 本次响应的ETag
+	LastModified string // 本次响应的Last-Modified
+	NotModified  bool   // 内容未变化，Items为空
+}
+
+// Fetcher 某一类来源的抓取实现
+type Fetcher func(ctx context.Context, req *Request) (*Result, error)
+
+var (
+	mu       sync.RWMutex
+	fetchers = make(map[string]Fetcher)
+
+	httpClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// Register 注册来源类型的抓取实现
+func Register(sourceType string, f Fetcher) {
+	mu.Lock()
+	defer mu.Unlock()
+	fetchers[sourceType] = f
+}
+
+// Supported 判断来源类型是否已支持
+func Supported(sourceType string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := fetchers[sourceType]
+	return ok
+}
+
+// Types 返回已支持的来源类型
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	types := make([]string, 0, len(fetchers))
+	for t := range fetchers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Fetch 按来源类型抓取条目
+func Fetch(ctx context.Context, req *Request) (*Result, error) {
+	mu.RLock()
+	f, ok := fetchers[req.Type]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("不支持的来源类型: %s", req.Type)
+	}
+	return f(ctx, req)
+}
+
+// response 抓取到的原始响应
+type response struct {
+	body         []byte
+	contentType  string
+	etag         string
+	lastModified string
+	notModified  bool
+}
+
+// get 发送条件GET请求并读取响应体，304视为未变化，其他非200状态码视为错误
+func get(ctx context.Context, r *Request, accept string) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", "go-nextjs-feed/1.0")
+	if r.ETag != "" {
+		req.Header.Set("If-None-Match", r.ETag)
+	}
+	if r.LastModified != "" {
+		req.Header.Set("If-Modified-Since", r.LastModified)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return &response{etag: r.ETag, lastModified: r.LastModified, notModified: true}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("来源返回非成功状态码: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+	return &response{
+		body:         body,
+		contentType:  resp.Header.Get("Content-Type"),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// newResult 根据响应创建抓取结果，记录条件请求所需的缓存标识
+func newResult(resp *response, capacity int) *Result {
+	return &Result{
+		Items:        make([]Item, 0, capacity),
+		ETag:         resp.etag,
+		LastModified: resp.lastModified,
+		NotModified:  resp.notModified,
+	}
+}
@@ -0,0 +1,159 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go-nextjs/models"
+	"go-nextjs/pkg/spec"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html/charset"
+)
+
+// fieldAttrPattern 匹配字段表达式结尾的 "@属性"
+var fieldAttrPattern = regexp.MustCompile(`@\s*([A-Za-z_:][-\w:.]*)$`)
+
+func init() {
+	Register(models.SourceTypeHTML, fetchHTML)
+}
+
+// fetchHTML 按CSS选择器规则从HTML页面中提取条目
+func fetchHTML(ctx context.Context, req *Request) (*Result, error) {
+	if err := ValidateSelectors(&req.Selectors); err != nil {
+		return nil, err
+	}
+
+	resp, err := get(ctx, req, "text/html, application/xhtml+xml;q=0.9, */*;q=0.8")
+	if err != nil {
+		return nil, err
+	}
+	if resp.notModified {
+		return newResult(resp, 0), nil
+	}
+
+	// 按Content-Type或页面中的<meta charset>转换为UTF-8，国内论坛常用GBK
+	reader, err := charset.NewReader(bytes.NewReader(resp.body), resp.contentType)
+	if err != nil {
+		reader = bytes.NewReader(resp.body)
+	}
+	doc, err := goquery.NewDocumentFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("解析HTML失败: %v", err)
+	}
+	base, _ := url.Parse(req.URL)
+
+	rule := &req.Selectors
+	containers := doc.Find(rule.Item)
+	result := newResult(resp, containers.Length())
+	seen := make(map[string]bool)
+	containers.Each(func(_ int, s *goquery.Selection) {
+		title := extractField(s, rule.Title, "")
+		if title == "" {
+			return
+		}
+
+		link := extractField(s, rule.Link, "href")
+		if link != "" && base != nil {
+			if u, err := base.Parse(link); err == nil {
+				link = u.String()
+			}
+		}
+
+		// 没有链接时用标题区分条目
+		id := link
+		if id == "" {
+			id = title
+		}
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+
+		description := extractField(s, rule.Description, "")
+		price := spec.ParsePrice(extractField(s, rule.Price, ""))
+		if price.Amount == 0 {
+			price = spec.ParsePrice(title)
+		}
+
+		result.Items = append(result.Items, Item{
+			ExternalID:   id,
+			Title:        title,
+			Description:  description,
+			Price:        price.Amount,
+			Currency:     price.Currency,
+			BillingCycle: price.BillingCycle,
+			URL:          link,
+			Enrich:       true,
+		})
+	})
+	return result, nil
+}
+
+// ValidateSelectors 校验HTML来源的选择器规则，条目容器和标题为必填
+func ValidateSelectors(rule *models.SelectorRule) error {
+	if strings.TrimSpace(rule.Item) == "" {
+		return fmt.Errorf("缺少条目容器选择器")
+	}
+	if strings.TrimSpace(rule.Title) == "" {
+		return fmt.Errorf("缺少标题选择器")
+	}
+	if _, err := cascadia.Compile(rule.Item); err != nil {
+		return fmt.Errorf("无效的条目容器选择器 %q: %v", rule.Item, err)
+	}
+
+	fields := map[string]string{
+		"标题": rule.Title,
+		"价格": rule.Price,
+		"链接": rule.Link,
+		"描述": rule.Description,
+	}
+	for name, expr := range fields {
+		selector, _ := splitField(expr)
+		if selector == "" {
+			continue
+		}
+		if _, err := cascadia.Compile(selector); err != nil {
+			return fmt.Errorf("无效的%s选择器 %q: %v", name, expr, err)
+		}
+	}
+	return nil
+}
+
+// extractField 在容器内按 "选择器@属性" 提取文本，未指定属性时使用defaultAttr，defaultAttr为空时读取文本
+func extractField(s *goquery.Selection, expr string, defaultAttr string) string {
+	if strings.TrimSpace(expr) == "" {
+		return ""
+	}
+
+	selector, attr := splitField(expr)
+	target := s
+	if selector != "" {
+		target = s.Find(selector).First()
+	}
+	if target.Length() == 0 {
+		return ""
+	}
+
+	if attr == "" {
+		attr = defaultAttr
+	}
+	if attr != "" {
+		value, _ := target.Attr(attr)
+		return strings.TrimSpace(value)
+	}
+	return strings.Join(strings.Fields(target.Text()), " ")
+}
+
+// splitField 拆分 "选择器@属性" 表达式，只有结尾的合法属性名才视为属性，选择器本身可以包含@
+func splitField(expr string) (string, string) {
+	expr = strings.TrimSpace(expr)
+	if loc := fieldAttrPattern.FindStringSubmatchIndex(expr); loc != nil {
+		return strings.TrimSpace(expr[:loc[0]]), expr[loc[2]:loc[3]]
+	}
+	return expr, ""
+}
@@ -0,0 +1,99 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-nextjs/models"
+)
+
+// fetchPage 通过测试服务抓取HTML页面
+func fetchPage(t *testing.T, contentType, body string, selectors models.SelectorRule) *Result {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	result, err := Fetch(context.Background(), &Request{Type: models.SourceTypeHTML, URL: server.URL, Selectors: selectors})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestFetchHTMLCharsets(t *testing.T) {
+	const wantTitle = "搬瓦工 香港CN2 优惠 $49.99/年"
+	// "搬瓦工 香港CN2 优惠 $49.99/年" 的GBK编码
+	const gbkTitle = "\xb0\xe1\xcd\xdf\xb9\xa4 \xcf\xe3\xb8\xdbCN2 \xd3\xc5\xbb\xdd $49.99/\xc4\xea"
+	selectors := models.SelectorRule{Item: ".deal", Title: "a", Link: "a"}
+	item := `<div class="deal"><a href="/t/1">` + gbkTitle + `</a></div>`
+
+	tests := []struct {
+		name        string
+		contentType string
+		head        string
+	}{
+		{"content type", "text/html; charset=gbk", ""},
+		{"meta charset", "text/html", `<meta charset="gbk">`},
+		{"meta http-equiv", "text/html", `<meta http-equiv="Content-Type" content="text/html; charset=gb2312">`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := fetchPage(t, tt.contentType, "<html><head>"+tt.head+"</head><body>"+item+"</body></html>", selectors)
+			if len(result.Items) != 1 {
+				t.Fatalf("items = %d, want 1", len(result.Items))
+			}
+			if got := result.Items[0]; got.Title != wantTitle || got.Price != 49.99 || got.BillingCycle != models.BillingAnnually {
+				t.Errorf("item = %q %v %s", got.Title, got.Price, got.BillingCycle)
+			}
+		})
+	}
+}
+
+func TestSplitField(t *testing.T) {
+	tests := []struct {
+		expr, selector, attr string
+	}{
+		{"a", "a", ""},
+		{"a@href", "a", "href"},
+		{" a.buy @ data-url ", "a.buy", "data-url"},
+		{"@href", "", "href"},
+		{"img@xlink:href", "img", "xlink:href"},
+		{`a[href^="mailto:"]`, `a[href^="mailto:"]`, ""},
+		{`a[title*="@"]`, `a[title*="@"]`, ""},
+		{`a[title*="@"]@href`, `a[title*="@"]`, "href"},
+		{`a[data-x="@1"]`, `a[data-x="@1"]`, ""},
+	}
+	for _, tt := range tests {
+		if selector, attr := splitField(tt.expr); selector != tt.selector || attr != tt.attr {
+			t.Errorf("splitField(%q) = %q, %q, want %q, %q", tt.expr, selector, attr, tt.selector, tt.attr)
+		}
+	}
+}
+
+func TestFetchHTMLAttributes(t *testing.T) {
+	selectors := models.SelectorRule{
+		Item:  ".deal",
+		Title: `a[title*="@"]@title`,
+		Price: "span@data-price",
+		Link:  "a@href",
+	}
+	result := fetchPage(t, "text/html; charset=utf-8", `<html><body>
+<div class="deal"><a href="/cart?pid=1" title="KVM @ LA">Order</a><span data-price="$5/mo">Sale</span></div>
+</body></html>`, selectors)
+	if len(result.Items) != 1 {
+		t.Fatalf("items = %d, want 1", len(result.Items))
+	}
+	item := result.Items[0]
+	if item.Title != "KVM @ LA" || item.Price != 5 || item.BillingCycle != models.BillingMonthly {
+		t.Errorf("item = %q %v %s", item.Title, item.Price, item.BillingCycle)
+	}
+	if !strings.HasSuffix(item.URL, "/cart?pid=1") {
+		t.Errorf("url = %q", item.URL)
+	}
+}
@@ -0,0 +1,155 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-nextjs/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(models.SourceTypeJSON, fetchJSON)
+}
+
+// jsonItem JSON接口中的单条优惠，字段名与创建优惠的接口一致
+type jsonItem struct {
+	ID           flexString `json:"id"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	Provider     string     `json:"provider"`
+	Price        flexFloat  `json:"price"`
+	Currency     string     `json:"currency"`
+	BillingCycle string     `json:"billing_cycle"`
+	URL          string     `json:"url"`
+	CPU          string     `json:"cpu"`
+	RAM          string     `json:"ram"`
+	Disk         string     `json:"disk"`
+	Bandwidth    string     `json:"bandwidth"`
+	IP           string     `json:"ip"`
+	Location     string     `json:"location"`
+	Remark       string     `json:"remark"`
+	EndsAt       string     `json:"ends_at"` // RFC3339 或 2006-01-02
+}
+
+// fetchJSON 抓取JSON接口，支持顶层数组或包含deals/items/data数组的对象
+func fetchJSON(ctx context.Context, req *Request) (*Result, error) {
+	resp, err := get(ctx, req, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	if resp.notModified {
+		return newResult(resp, 0), nil
+	}
+
+	raw, err := jsonItems(resp.body)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []jsonItem
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("解析JSON条目失败: %v", err)
+	}
+
+	result := newResult(resp, len(list))
+	for _, it := range list {
+		item := Item{
+			ExternalID:   string(it.ID),
+			Title:        strings.TrimSpace(it.Title),
+			Description:  it.Description,
+			Provider:     it.Provider,
+			Price:        float64(it.Price),
+			Currency:     it.Currency,
+			BillingCycle: it.BillingCycle,
+			URL:          strings.TrimSpace(it.URL),
+		}
+		item.CPU = it.CPU
+		item.RAM = it.RAM
+		item.Disk = it.Disk
+		item.Bandwidth = it.Bandwidth
+		item.IP = it.IP
+		item.Location = it.Location
+		item.Remark = it.Remark
+		if item.ExternalID == "" {
+			item.ExternalID = item.URL
+		}
+		item.EndsAt = parseTime(it.EndsAt)
+		result.Items = append(result.Items, item)
+	}
+	return result, nil
+}
+
+// jsonItems 定位响应中的条目数组
+func jsonItems(body []byte) (json.RawMessage, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		return body, nil
+	}
+
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(body, &wrapper); err != nil {
+		return nil, fmt.Errorf("解析JSON失败: %v", err)
+	}
+	for _, key := range []string{"deals", "items", "data"} {
+		if raw, ok := wrapper[key]; ok {
+			return raw, nil
+		}
+	}
+	return nil, fmt.Errorf("JSON中没有找到条目数组")
+}
+
+// flexString 兼容数字和字符串的ID
+type flexString string
+
+func (s *flexString) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*s = flexString(str)
+		return nil
+	}
+	*s = flexString(strings.Trim(string(data), `"`))
+	if *s == "null" {
+		*s = ""
+	}
+	return nil
+}
+
+// parseTime 解析RFC3339或日期格式的时间，无法解析时返回nil
+func parseTime(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// pricePattern 从价格文本中提取数字
+var pricePattern = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// flexFloat 兼容数字和 "$5.99" 这类字符串的价格
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(data []byte) error {
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*f = flexFloat(number)
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return nil
+	}
+	if m := pricePattern.FindString(strings.ReplaceAll(str, ",", "")); m != "" {
+		number, _ = strconv.ParseFloat(m, 64)
+		*f = flexFloat(number)
+	}
+	return nil
+}
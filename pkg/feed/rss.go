@@ -0,0 +1,171 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"go-nextjs/models"
+	"go-nextjs/pkg/spec"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+func init() {
+	Register(models.SourceTypeRSS, fetchRSS)
+}
+
+// maxDescriptionLength 交给AI分析的描述最大长度
+const maxDescriptionLength = 4000
+
+// xmlFeed 同时兼容RSS 2.0、RSS 1.0和Atom的文档结构
+type xmlFeed struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`  // RSS 1.0的条目位于根节点
+	Entries []atomEntry `xml:"entry"` // Atom
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+type atomEntry struct {
+	ID      string     `xml:"id"`
+	Title   string     `xml:"title"`
+	Links   []atomLink `xml:"link"`
+	Summary string     `xml:"summary"`
+	Content string     `xml:"content"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// fetchRSS 抓取RSS/Atom订阅，按GUID和链接去重
+func fetchRSS(ctx context.Context, req *Request) (*Result, error) {
+	resp, err := get(ctx, req, "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+	if err != nil {
+		return nil, err
+	}
+	if resp.notModified {
+		return newResult(resp, 0), nil
+	}
+
+	var doc xmlFeed
+	decoder := xml.NewDecoder(bytes.NewReader(resp.body))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = charsetReader
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("解析订阅失败: %v", err)
+	}
+
+	items := append(doc.Channel.Items, doc.Items...)
+	result := newResult(resp, len(items)+len(doc.Entries))
+	seen := make(map[string]bool)
+	add := func(id, link, title, body string) {
+		id = strings.TrimSpace(id)
+		link = strings.TrimSpace(link)
+		if id == "" {
+			id = link
+		}
+		if id == "" || seen[id] || (link != "" && seen[link]) {
+			return
+		}
+		seen[id] = true
+		if link != "" {
+			seen[link] = true
+		}
+		result.Items = append(result.Items, newFeedItem(id, link, title, body))
+	}
+
+	for _, it := range items {
+		body := it.Content
+		if body == "" {
+			body = it.Description
+		}
+		add(it.GUID, it.Link, it.Title, body)
+	}
+	for _, e := range doc.Entries {
+		body := e.Content
+		if body == "" {
+			body = e.Summary
+		}
+		add(e.ID, atomHref(e.Links), e.Title, body)
+	}
+	return result, nil
+}
+
+// newFeedItem 将订阅条目转为待AI分析的优惠条目，价格从标题和正文中解析
+func newFeedItem(id, link, title, body string) Item {
+	title = strings.TrimSpace(html.UnescapeString(title))
+	text := stripHTML(body)
+	if runes := []rune(text); len(runes) > maxDescriptionLength {
+		text = string(runes[:maxDescriptionLength])
+	}
+
+	price := spec.ParsePrice(title)
+	if price.Amount == 0 {
+		price = spec.ParsePrice(text)
+	}
+
+	return Item{
+		ExternalID:   id,
+		Title:        title,
+		Description:  text,
+		Price:        price.Amount,
+		Currency:     price.Currency,
+		BillingCycle: price.BillingCycle,
+		URL:          link,
+		Enrich:       true,
+	}
+}
+
+// atomHref 选取Atom条目的正文链接
+func atomHref(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+var (
+	tagPattern   = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]+>`)
+	spacePattern = regexp.MustCompile(`[ \t\r\f\v]+`)
+	linesPattern = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// stripHTML 去除HTML标签并合并空白，保留段落换行
+func stripHTML(s string) string {
+	s = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n", "</li>", "\n", "</div>", "\n").Replace(s)
+	s = tagPattern.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	s = spacePattern.ReplaceAllString(s, " ")
+	s = linesPattern.ReplaceAllString(s, "\n")
+	return strings.TrimSpace(s)
+}
+
+// charsetReader 将声明了其他编码（如GBK、GB2312、Big5）的订阅转换为UTF-8
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	reader, err := charset.NewReaderLabel(label, input)
+	if err != nil {
+		return nil, fmt.Errorf("不支持的订阅编码: %s", label)
+	}
+	return reader, nil
+}
@@ -0,0 +1,56 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-nextjs/models"
+)
+
+// fetchFeed 通过测试服务抓取订阅内容
+func fetchFeed(t *testing.T, body string) (*Result, error) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	return Fetch(context.Background(), &Request{Type: models.SourceTypeRSS, URL: server.URL})
+}
+
+func TestFetchRSSCharsets(t *testing.T) {
+	const wantTitle = "搬瓦工 香港CN2 优惠 $49.99/年"
+	// "搬瓦工 香港CN2 优惠 $49.99/年" 和 "1核 1G内存" 的GBK编码
+	const gbkTitle = "\xb0\xe1\xcd\xdf\xb9\xa4 \xcf\xe3\xb8\xdbCN2 \xd3\xc5\xbb\xdd $49.99/\xc4\xea"
+	const gbkDescription = "1\xba\xcb 1G\xc4\xda\xb4\xe6"
+
+	for _, label := range []string{"GBK", "gb2312", "GB18030"} {
+		t.Run(label, func(t *testing.T) {
+			result, err := fetchFeed(t, `<?xml version="1.0" encoding="`+label+`"?>
+<rss version="2.0"><channel><item><title>`+gbkTitle+`</title><link>https://bbs.test/1</link>
+<description>`+gbkDescription+`</description></item></channel></rss>`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Items) != 1 {
+				t.Fatalf("items = %d, want 1", len(result.Items))
+			}
+			item := result.Items[0]
+			if item.Title != wantTitle || item.Description != "1核 1G内存" {
+				t.Errorf("item = %q / %q", item.Title, item.Description)
+			}
+			if item.Price != 49.99 || item.BillingCycle != models.BillingAnnually {
+				t.Errorf("price = %v %s", item.Price, item.BillingCycle)
+			}
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := fetchFeed(t, `<?xml version="1.0" encoding="x-unknown"?><rss><channel></channel></rss>`)
+		if err == nil {
+			t.Fatal("Fetch() error = nil, want unsupported encoding")
+		}
+	})
+}
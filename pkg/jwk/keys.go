@@ -0,0 +1,405 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"go-nextjs/config"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	// JWKSMaxAge JWKS响应允许客户端缓存的时间
+	JWKSMaxAge = 5 * time.Minute
+	// reloadInterval 从磁盘重新加载密钥的最小间隔，用于同步其他实例生成的密钥
+	reloadInterval = time.Minute
+	// activationDelay 轮换生成的新密钥先在JWKS中发布，经过该时间后才开始签名，
+	// 保证其他实例重新加载、客户端缓存的JWKS过期后都已拿到新公钥
+	activationDelay = JWKSMaxAge + reloadInterval
+)
+
+// signingKey 单个签名密钥
+type signingKey struct {
+	kid         string
+	alg         string
+	private     crypto.Signer
+	createdAt   time.Time
+	activatesAt time.Time // 开始签名的时间，之前只发布公钥
+	path        string
+}
+
+// Manager 管理JWT签名密钥的生成、轮换和发布
+//
+// 密钥以PEM格式保存在数据目录中。轮换时新密钥先在JWKS中发布，经过activationDelay
+// 后才开始签名，避免客户端缓存的JWKS中还没有新公钥；旧密钥继续保留，
+// 直到它签发的令牌全部过期，期间仍可用于验证并在JWKS中发布。
+type Manager struct {
+	dir         string
+	alg         string
+	rotation    time.Duration // 轮换周期
+	maxTokenAge time.Duration // 令牌最长有效期，决定旧密钥的保留时间
+
+	mu         sync.RWMutex
+	keys       []*signingKey // 按创建时间升序，最后一个已到启用时间的为当前签名密钥
+	lastReload time.Time
+}
+
+var defaultManager *Manager
+
+// Init 根据配置初始化默认的密钥管理器
+func Init() error {
+	m, err := NewManager(filepath.Join(config.DataDir, "keys"), config.JWTAlgorithm, config.JWTKeyRotation, config.JWTExpire)
+	if err != nil {
+		return err
+	}
+	defaultManager = m
+	return nil
+}
+
+// NewManager 创建密钥管理器，目录中没有可用密钥时自动生成
+func NewManager(dir string, alg string, rotation time.Duration, maxTokenAge time.Duration) (*Manager, error) {
+	if alg != jwt.SigningMethodRS256.Alg() && alg != jwt.SigningMethodEdDSA.Alg() {
+		return nil, fmt.Errorf("不支持的签名算法: %s，可选 RS256 或 EdDSA", alg)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建密钥目录失败: %v", err)
+	}
+
+	m := &Manager{
+		dir:         dir,
+		alg:         alg,
+		rotation:    rotation,
+		maxTokenAge: maxTokenAge,
+	}
+	// Rotate会先从磁盘加载已有密钥
+	if _, err := m.Rotate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Rotate 最新的密钥超过轮换周期时生成新密钥，并清理已无令牌依赖的旧密钥
+//
+// 没有任何密钥时生成的密钥立即启用，否则新密钥在activationDelay后才开始签名。
+func (m *Manager) Rotate() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 先同步其他实例可能已生成的密钥，避免重复轮换
+	if err := m.loadLocked(); err != nil {
+		return false, err
+	}
+
+	rotated := false
+	var latest *signingKey
+	if len(m.keys) > 0 {
+		latest = m.keys[len(m.keys)-1]
+	}
+	if latest == nil || latest.alg != m.alg || time.Since(latest.createdAt) >= m.rotation {
+		delay := activationDelay
+		if latest == nil {
+			delay = 0
+		}
+		key, err := m.generate(delay)
+		if err != nil {
+			return false, err
+		}
+		m.keys = append(m.keys, key)
+		rotated = true
+		log.Printf("已生成新的JWT签名密钥: %s (%s)，将于 %s 开始签名",
+			key.kid, key.alg, key.activatesAt.Format(time.RFC3339))
+	}
+
+	m.prune()
+	return rotated, nil
+}
+
+// Sign 使用当前密钥签名，并在头部写入kid
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	key := m.active()
+	m.mu.RUnlock()
+	if key == nil {
+		return "", fmt.Errorf("没有可用的签名密钥")
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.alg), claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// Keyfunc 根据kid查找验证公钥，供jwt.Parse使用
+func (m *Manager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key := m.find(kid)
+	if key == nil {
+		// 其他实例可能已轮换密钥，重新从磁盘加载
+		m.reload()
+		key = m.find(kid)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("未知的签名密钥: %s", kid)
+	}
+	// 算法必须与密钥一致，防止算法混淆攻击
+	if token.Method.Alg() != key.alg {
+		return nil, fmt.Errorf("签名算法不匹配: %s", token.Method.Alg())
+	}
+	return key.private.Public(), nil
+}
+
+// JWKS 返回所有待启用和仍在有效期内的公钥
+func (m *Manager) JWKS() Set {
+	// 定期从磁盘重新加载，及时发布其他实例生成的待启用密钥
+	m.reload()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := Set{Keys: make([]JSONWebKey, 0, len(m.keys))}
+	// 较新的密钥排在最前
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		key := m.keys[i]
+		k, err := NewJSONWebKey(key.private.Public(), key.kid, key.alg)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, k)
+	}
+	return set
+}
+
+// active 当前签名密钥，即已到启用时间的最新密钥，调用方需持有锁
+func (m *Manager) active() *signingKey {
+	now := time.Now()
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		if !m.keys[i].activatesAt.After(now) {
+			return m.keys[i]
+		}
+	}
+	// 所有密钥都未到启用时间时使用最早的密钥
+	if len(m.keys) > 0 {
+		return m.keys[0]
+	}
+	return nil
+}
+
+// find 根据kid查找密钥
+func (m *Manager) find(kid string) *signingKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.keys {
+		if key.kid == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+// reload 按最小间隔从磁盘重新加载密钥
+func (m *Manager) reload() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if time.Since(m.lastReload) < reloadInterval {
+		return
+	}
+	if err := m.loadLocked(); err != nil {
+		log.Printf("重新加载JWT签名密钥失败: %v", err)
+	}
+}
+
+// loadLocked 从目录加载全部密钥，调用方需持有锁
+func (m *Manager) loadLocked() error {
+	files, err := filepath.Glob(filepath.Join(m.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*signingKey, 0, len(files))
+	for _, file := range files {
+		key, err := readKey(file)
+		if err != nil {
+			log.Printf("跳过无法读取的签名密钥 %s: %v", file, err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].createdAt.Before(keys[j].createdAt)
+	})
+
+	m.keys = keys
+	m.lastReload = time.Now()
+	return nil
+}
+
+// prune 删除已被替换且签发的令牌均已过期的密钥，调用方需持有锁
+func (m *Manager) prune() {
+	kept := m.keys[:0]
+	for i, key := range m.keys {
+		if i < len(m.keys)-1 {
+			// 旧密钥在下一个密钥启用时停止签名
+			retiredAt := m.keys[i+1].activatesAt
+			if time.Since(retiredAt) > m.maxTokenAge+time.Minute {
+				if err := os.Remove(key.path); err != nil && !os.IsNotExist(err) {
+					log.Printf("删除过期签名密钥失败: %v", err)
+				}
+				log.Printf("已移除过期的JWT签名密钥: %s", key.kid)
+				continue
+			}
+		}
+		kept = append(kept, key)
+	}
+	m.keys = kept
+}
+
+// generate 生成新密钥并写入磁盘，经过delay后开始签名，调用方需持有锁
+func (m *Manager) generate(delay time.Duration) (*signingKey, error) {
+	var private crypto.Signer
+	var err error
+	switch m.alg {
+	case jwt.SigningMethodEdDSA.Alg():
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("生成签名密钥失败: %v", err)
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	key := &signingKey{
+		kid:         now.Format("20060102") + "-" + hex.EncodeToString(suffix),
+		alg:         m.alg,
+		private:     private,
+		createdAt:   now,
+		activatesAt: now.Add(delay),
+	}
+	key.path = filepath.Join(m.dir, key.kid+".pem")
+
+	if err := writeKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// writeKey 以PKCS8格式写入私钥，算法、创建时间和启用时间保存在PEM头部
+func writeKey(key *signingKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.private)
+	if err != nil {
+		return fmt.Errorf("序列化签名密钥失败: %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{
+		Type: "PRIVATE KEY",
+		Headers: map[string]string{
+			"Alg":       key.alg,
+			"Created":   key.createdAt.Format(time.RFC3339Nano),
+			"Activates": key.activatesAt.Format(time.RFC3339Nano),
+		},
+		Bytes: der,
+	})
+
+	// 先写临时文件再重命名，避免其他实例读到不完整的密钥
+	tmp := key.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("保存签名密钥失败: %v", err)
+	}
+	return os.Rename(tmp, key.path)
+}
+
+// readKey 读取PEM格式的私钥
+func readKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("无效的PEM文件")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("不支持的私钥类型: %T", parsed)
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, block.Headers["Created"])
+	if err != nil {
+		return nil, fmt.Errorf("无效的创建时间: %v", err)
+	}
+	// 早期生成的密钥没有启用时间，创建后即开始签名
+	activatesAt := createdAt
+	if value := block.Headers["Activates"]; value != "" {
+		if activatesAt, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, fmt.Errorf("无效的启用时间: %v", err)
+		}
+	}
+
+	alg := block.Headers["Alg"]
+	if jwt.GetSigningMethod(alg) == nil {
+		return nil, fmt.Errorf("不支持的签名算法: %s", alg)
+	}
+
+	return &signingKey{
+		kid:         strings.TrimSuffix(filepath.Base(path), ".pem"),
+		alg:         alg,
+		private:     private,
+		createdAt:   createdAt,
+		activatesAt: activatesAt,
+		path:        path,
+	}, nil
+}
+
+// Sign 使用默认密钥管理器签名
+func Sign(claims jwt.Claims) (string, error) {
+	if defaultManager == nil {
+		return "", fmt.Errorf("签名密钥未初始化")
+	}
+	return defaultManager.Sign(claims)
+}
+
+// Keyfunc 使用默认密钥管理器查找验证公钥
+func Keyfunc(token *jwt.Token) (interface{}, error) {
+	if defaultManager == nil {
+		return nil, fmt.Errorf("签名密钥未初始化")
+	}
+	return defaultManager.Keyfunc(token)
+}
+
+// PublicJWKS 返回默认密钥管理器发布的公钥集合
+func PublicJWKS() Set {
+	if defaultManager == nil {
+		return Set{Keys: []JSONWebKey{}}
+	}
+	return defaultManager.JWKS()
+}
+
+// Rotate 检查并轮换默认密钥管理器的签名密钥
+func Rotate() (bool, error) {
+	if defaultManager == nil {
+		return false, fmt.Errorf("签名密钥未初始化")
+	}
+	return defaultManager.Rotate()
+}
@@ -0,0 +1,173 @@
+package jwk
+
+import (
+	"encoding/pem"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	testRotation    = time.Hour
+	testMaxTokenAge = 15 * time.Minute
+)
+
+func newTestManager(t *testing.T, dir string) *Manager {
+	t.Helper()
+	m, err := NewManager(dir, "EdDSA", testRotation, testMaxTokenAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// shift 将密钥的创建和启用时间提前d并写回磁盘，模拟时间流逝
+func shift(t *testing.T, m *Manager, d time.Duration) {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range m.keys {
+		key.createdAt = key.createdAt.Add(-d)
+		key.activatesAt = key.activatesAt.Add(-d)
+		if err := writeKey(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// signedKid 签名一个令牌并返回其kid，同时确认令牌可以验证
+func signedKid(t *testing.T, m *Manager) string {
+	t.Helper()
+	signed, err := m.Sign(jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Parse(signed, m.Keyfunc)
+	if err != nil || !token.Valid {
+		t.Fatalf("令牌验证失败: %v", err)
+	}
+	return token.Header["kid"].(string)
+}
+
+func publishedKids(m *Manager) []string {
+	var kids []string
+	for _, k := range m.JWKS().Keys {
+		kids = append(kids, k.Kid)
+	}
+	return kids
+}
+
+func TestRotatePublishesBeforeSigning(t *testing.T) {
+	m := newTestManager(t, t.TempDir())
+
+	// 首个密钥立即启用
+	if len(m.keys) != 1 {
+		t.Fatalf("keys = %d, want 1", len(m.keys))
+	}
+	first := m.keys[0].kid
+	if kid := signedKid(t, m); kid != first {
+		t.Fatalf("signing kid = %s, want %s", kid, first)
+	}
+
+	// 未到轮换周期时不生成新密钥
+	if rotated, err := m.Rotate(); err != nil || rotated {
+		t.Fatalf("Rotate() = %v, %v, want no rotation", rotated, err)
+	}
+
+	shift(t, m, testRotation)
+	rotated, err := m.Rotate()
+	if err != nil || !rotated {
+		t.Fatalf("Rotate() = %v, %v, want rotation", rotated, err)
+	}
+	second := m.keys[1].kid
+
+	// 新密钥先发布，但在缓存过期前仍用旧密钥签名
+	if kids := publishedKids(m); len(kids) != 2 || kids[0] != second || kids[1] != first {
+		t.Fatalf("JWKS kids = %v, want [%s %s]", kids, second, first)
+	}
+	if kid := signedKid(t, m); kid != first {
+		t.Fatalf("signing kid = %s before activation, want %s", kid, first)
+	}
+	if got := m.keys[1].activatesAt.Sub(m.keys[1].createdAt); got < JWKSMaxAge {
+		t.Errorf("activation delay = %v, want at least %v", got, JWKSMaxAge)
+	}
+
+	// 待启用的密钥不会被再次轮换
+	if rotated, err := m.Rotate(); err != nil || rotated {
+		t.Fatalf("Rotate() = %v, %v, want no rotation while pending", rotated, err)
+	}
+
+	// 到达启用时间后切换到新密钥，旧密钥继续发布以验证已签发的令牌
+	shift(t, m, activationDelay)
+	if _, err := m.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if kid := signedKid(t, m); kid != second {
+		t.Fatalf("signing kid = %s after activation, want %s", kid, second)
+	}
+	if kids := publishedKids(m); len(kids) != 2 {
+		t.Fatalf("JWKS kids = %v, want old key still published", kids)
+	}
+
+	// 旧密钥签发的令牌全部过期后移除
+	oldPath := m.keys[0].path
+	shift(t, m, testMaxTokenAge+2*time.Minute)
+	if _, err := m.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if kids := publishedKids(m); len(kids) != 1 || kids[0] != second {
+		t.Fatalf("JWKS kids = %v, want [%s]", kids, second)
+	}
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Errorf("old key file still exists: %v", err)
+	}
+}
+
+func TestPendingKeySharedBetweenInstances(t *testing.T) {
+	dir := t.TempDir()
+	a := newTestManager(t, dir)
+	first := a.keys[0].kid
+
+	shift(t, a, testRotation)
+	if rotated, err := a.Rotate(); err != nil || !rotated {
+		t.Fatalf("Rotate() = %v, %v, want rotation", rotated, err)
+	}
+
+	// 其他实例加载后同样发布待启用密钥，且不会提前用它签名或重复轮换
+	b := newTestManager(t, dir)
+	if len(b.keys) != 2 {
+		t.Fatalf("keys = %d, want 2", len(b.keys))
+	}
+	if kid := signedKid(t, b); kid != first {
+		t.Errorf("signing kid = %s, want %s", kid, first)
+	}
+	if !b.keys[1].activatesAt.Equal(a.keys[1].activatesAt) {
+		t.Errorf("activatesAt = %v, want %v", b.keys[1].activatesAt, a.keys[1].activatesAt)
+	}
+}
+
+func TestReadKeyWithoutActivation(t *testing.T) {
+	m := newTestManager(t, t.TempDir())
+	key := m.keys[0]
+
+	// 旧版本写入的密钥没有启用时间，视为创建后即启用
+	data, err := os.ReadFile(key.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	delete(block.Headers, "Activates")
+	if err := os.WriteFile(key.path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := readKey(key.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.activatesAt.Equal(loaded.createdAt) {
+		t.Errorf("activatesAt = %v, want createdAt %v", loaded.activatesAt, loaded.createdAt)
+	}
+}
@@ -0,0 +1,181 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"go-nextjs/config"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// httpClient 所有提供商共用的HTTP客户端
+var httpClient = &http.Client{Timeout: 20 * time.Second}
+
+// userMapper 将提供商返回的用户信息映射为统一格式
+type userMapper func(data map[string]interface{}) *UserInfo
+
+// oauth2Provider 标准OAuth2授权码流程的通用实现
+type oauth2Provider struct {
+	cfg     config.OAuthProviderConfig
+	mapUser userMapper
+}
+
+// Name 提供商名称
+func (p *oauth2Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL 构建带state和PKCE参数的授权URL
+func (p *oauth2Provider) AuthCodeURL(params AuthParams) string {
+	query := url.Values{}
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("response_type", "code")
+	query.Set("redirect_uri", p.cfg.RedirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", params.State)
+	if params.CodeVerifier != "" {
+		query.Set("code_challenge", CodeChallengeS256(params.CodeVerifier))
+		query.Set("code_challenge_method", "S256")
+	}
+
+	separator := "?"
+	if strings.Contains(p.cfg.AuthURL, "?") {
+		separator = "&"
+	}
+	return p.cfg.AuthURL + separator + query.Encode()
+}
+
+// Exchange 使用授权码和PKCE校验码换取令牌
+func (p *oauth2Provider) Exchange(ctx context.Context, code string, params AuthParams) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("client_secret", p.cfg.ClientSecret)
+	if params.CodeVerifier != "" {
+		form.Set("code_verifier", params.CodeVerifier)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	respBody, err := doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenResp struct {
+		Token
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(respBody, &tokenResp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	// 部分提供商（如GitHub）出错时仍返回200
+	if tokenResp.Error != "" {
+		return nil, fmt.Errorf("换取令牌失败: %s %s", tokenResp.Error, tokenResp.ErrorDescription)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("返回的access_token为空")
+	}
+
+	return &tokenResp.Token, nil
+}
+
+// UserInfo 请求用户信息接口并映射为统一格式
+func (p *oauth2Provider) UserInfo(ctx context.Context, token *Token) (*UserInfo, error) {
+	var data map[string]interface{}
+	if err := getJSON(ctx, p.cfg.UserinfoURL, token.AccessToken, &data); err != nil {
+		return nil, err
+	}
+
+	info := p.mapUser(data)
+	if info == nil || info.ID == "" {
+		return nil, fmt.Errorf("返回的用户ID为空")
+	}
+	return info, nil
+}
+
+// CodeChallengeS256 根据code_verifier计算PKCE的S256 code_challenge
+func CodeChallengeS256(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// getJSON 携带访问令牌请求JSON并解析到out
+func getJSON(ctx context.Context, endpoint string, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	respBody, err := doRequest(req)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(respBody))
+	decoder.UseNumber() // 保持数字ID的精度
+	if err := decoder.Decode(out); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	return nil
+}
+
+// doRequest 发送请求并返回响应体，非200状态码视为错误
+func doRequest(req *http.Request) ([]byte, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求返回非200状态码: %d, 响应: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+// claimBool 读取布尔类型的字段，部分提供商以字符串 "true" 返回
+func claimBool(data map[string]interface{}, key string) bool {
+	switch v := data[key].(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+// claimString 读取字符串或数字类型的字段
+func claimString(data map[string]interface{}, key string) string {
+	switch v := data[key].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
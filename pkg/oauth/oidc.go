@@ -0,0 +1,253 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"go-nextjs/config"
+	"go-nextjs/pkg/jwk"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	// jwksCacheTTL JWKS缓存时间
+	jwksCacheTTL = time.Hour
+	// jwksMinRefresh 遇到未知kid时强制刷新JWKS的最小间隔
+	jwksMinRefresh = time.Minute
+)
+
+// discoveryDocument OIDC发现文档
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcProvider 支持发现文档和ID Token校验的OIDC提供商
+type oidcProvider struct {
+	*oauth2Provider
+	issuer string
+	jwks   *jwksCache
+}
+
+// newOIDCFromIssuer 通过发现文档创建OIDC提供商，已显式配置的端点优先
+func newOIDCFromIssuer(ctx context.Context, cfg config.OAuthProviderConfig) (*oidcProvider, error) {
+	doc, err := discover(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	setDefaults(&cfg, doc.AuthorizationEndpoint, doc.TokenEndpoint, doc.UserinfoEndpoint,
+		"openid", "profile", "email")
+
+	return &oidcProvider{
+		oauth2Provider: &oauth2Provider{cfg: cfg, mapUser: mapOIDCUser},
+		issuer:         doc.Issuer,
+		jwks:           &jwksCache{uri: doc.JWKSURI},
+	}, nil
+}
+
+// discover 获取并校验OIDC发现文档
+func discover(ctx context.Context, issuer string) (*discoveryDocument, error) {
+	endpoint := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	respBody, err := doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取OIDC发现文档失败: %v", err)
+	}
+
+	var doc discoveryDocument
+	if err := json.Unmarshal(respBody, &doc); err != nil {
+		return nil, fmt.Errorf("解析OIDC发现文档失败: %v", err)
+	}
+	// 发现文档中的issuer必须与配置一致，防止被替换
+	if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("发现文档issuer不匹配: %s", doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("发现文档缺少必要的端点")
+	}
+	return &doc, nil
+}
+
+// AuthCodeURL 构建授权URL，附带nonce用于绑定ID Token
+func (p *oidcProvider) AuthCodeURL(params AuthParams) string {
+	authURL := p.oauth2Provider.AuthCodeURL(params)
+	if params.Nonce != "" {
+		authURL += "&nonce=" + url.QueryEscape(params.Nonce)
+	}
+	return authURL
+}
+
+// Exchange 换取令牌并校验ID Token
+func (p *oidcProvider) Exchange(ctx context.Context, code string, params AuthParams) (*Token, error) {
+	token, err := p.oauth2Provider.Exchange(ctx, code, params)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("返回的id_token为空")
+	}
+
+	claims, err := p.verifyIDToken(ctx, token.IDToken, params.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("校验id_token失败: %v", err)
+	}
+	token.Claims = claims
+	return token, nil
+}
+
+// UserInfo 使用ID Token中的声明映射用户信息，缺少邮箱时再查询用户信息接口
+func (p *oidcProvider) UserInfo(ctx context.Context, token *Token) (*UserInfo, error) {
+	if token.Claims == nil {
+		return nil, fmt.Errorf("缺少已校验的id_token")
+	}
+
+	info := mapOIDCUser(token.Claims)
+	if info.ID == "" {
+		return nil, fmt.Errorf("id_token中缺少sub")
+	}
+
+	if info.Email == "" && p.cfg.UserinfoURL != "" {
+		var data map[string]interface{}
+		if err := getJSON(ctx, p.cfg.UserinfoURL, token.AccessToken, &data); err == nil {
+			// 用户信息接口返回的sub必须与id_token一致
+			if claimString(data, "sub") == info.ID {
+				extra := mapOIDCUser(data)
+				info.Email = extra.Email
+				info.EmailVerified = extra.EmailVerified
+				if info.Nickname == "" {
+					info.Nickname = extra.Nickname
+				}
+				if info.Avatar == "" {
+					info.Avatar = extra.Avatar
+				}
+			}
+		}
+	}
+	return info, nil
+}
+
+// verifyIDToken 校验ID Token的签名、iss、aud、exp和nonce
+func (p *oidcProvider) verifyIDToken(ctx context.Context, raw string, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		// 只接受非对称签名算法，避免算法混淆攻击
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519, *jwt.SigningMethodRSAPSS:
+		default:
+			return nil, fmt.Errorf("不支持的签名算法: %s", token.Method.Alg())
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.jwks.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if iss, _ := claims["iss"].(string); iss != p.issuer {
+		return nil, fmt.Errorf("iss不匹配: %s", iss)
+	}
+	if !claims.VerifyAudience(p.cfg.ClientID, true) {
+		return nil, fmt.Errorf("aud不包含当前客户端")
+	}
+	// 多个受众时azp必须是当前客户端
+	if aud, ok := claims["aud"].([]interface{}); ok && len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.cfg.ClientID {
+			return nil, fmt.Errorf("azp不匹配: %s", azp)
+		}
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("id_token缺少exp或已过期")
+	}
+	if nonce != "" {
+		if got, _ := claims["nonce"].(string); got != nonce {
+			return nil, fmt.Errorf("nonce不匹配")
+		}
+	}
+	return claims, nil
+}
+
+// jwksCache 缓存提供商的签名公钥
+type jwksCache struct {
+	uri       string
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// key 根据kid获取公钥，缓存过期或遇到未知kid时刷新
+func (c *jwksCache) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expired := time.Since(c.fetchedAt) > jwksCacheTTL
+	_, found := c.keys[kid]
+	if expired || (!found && time.Since(c.fetchedAt) > jwksMinRefresh) {
+		if err := c.refresh(ctx); err != nil && c.keys == nil {
+			return nil, err
+		}
+	}
+
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	// 只有一个密钥且token未指定kid时直接使用
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("未找到签名公钥: %s", kid)
+}
+
+// refresh 重新下载JWKS
+func (c *jwksCache) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.uri, nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	respBody, err := doRequest(req)
+	if err != nil {
+		return fmt.Errorf("获取JWKS失败: %v", err)
+	}
+
+	var set struct {
+		Keys []jwk.JSONWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(respBody, &set); err != nil {
+		return fmt.Errorf("解析JWKS失败: %v", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.PublicKey()
+		if err != nil {
+			continue // 忽略无法识别的密钥
+		}
+		keys[k.Kid] = key
+	}
+
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,388 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-nextjs/config"
+	"go-nextjs/pkg/jwk"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// fakeIssuer 模拟OIDC提供商，发布发现文档和JWKS，并签发ID Token
+type fakeIssuer struct {
+	server   *httptest.Server
+	jwksHits int32 // JWKS请求次数
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PrivateKey // 当前发布的密钥
+	doc  map[string]interface{}     // 覆盖发现文档的字段
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	f := &fakeIssuer{keys: map[string]*rsa.PrivateKey{}, doc: map[string]interface{}{}}
+	f.addKey(t, "k1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		doc := map[string]interface{}{
+			"issuer":                 f.server.URL,
+			"authorization_endpoint": f.server.URL + "/authorize",
+			"token_endpoint":         f.server.URL + "/token",
+			"userinfo_endpoint":      f.server.URL + "/userinfo",
+			"jwks_uri":               f.server.URL + "/jwks",
+		}
+		f.mu.Lock()
+		for k, v := range f.doc {
+			doc[k] = v
+		}
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(doc)
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&f.jwksHits, 1)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		set := jwk.Set{}
+		for kid, key := range f.keys {
+			k, err := jwk.NewJSONWebKey(&key.PublicKey, kid, "RS256")
+			if err != nil {
+				t.Error(err)
+			}
+			set.Keys = append(set.Keys, k)
+		}
+		json.NewEncoder(w).Encode(set)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "at",
+			"token_type":   "Bearer",
+			"id_token":     f.sign(t, "k1", f.claims("client", r.Form.Get("code"))),
+		})
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// addKey 生成并发布新的签名密钥
+func (f *fakeIssuer) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	f.keys[kid] = key
+	f.mu.Unlock()
+	return key
+}
+
+// claims 返回一组有效的ID Token声明
+func (f *fakeIssuer) claims(aud interface{}, nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   f.server.URL,
+		"sub":   "user-1",
+		"aud":   aud,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": nonce,
+		"email": "alice@example.com",
+	}
+}
+
+// sign 使用已发布的密钥签名
+func (f *fakeIssuer) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	f.mu.Lock()
+	key := f.keys[kid]
+	f.mu.Unlock()
+	return signWith(t, key, kid, claims)
+}
+
+func signWith(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// provider 通过发现文档创建指向假提供商的OIDC提供商
+func (f *fakeIssuer) provider(t *testing.T) *oidcProvider {
+	t.Helper()
+	p, err := newOIDCFromIssuer(context.Background(), config.OAuthProviderConfig{
+		Name:     "fake",
+		Type:     "oidc",
+		ClientID: "client",
+		Issuer:   f.server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	f := newFakeIssuer(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		nonce   string
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			token: func() string { return f.sign(t, "k1", f.claims("client", "n1")) },
+			nonce: "n1",
+		},
+		{
+			name: "multiple audiences with azp",
+			token: func() string {
+				c := f.claims([]string{"client", "other"}, "n1")
+				c["azp"] = "client"
+				return f.sign(t, "k1", c)
+			},
+			nonce: "n1",
+		},
+		{
+			name:    "bad signature",
+			token:   func() string { return signWith(t, otherKey, "k1", f.claims("client", "n1")) },
+			nonce:   "n1",
+			wantErr: "verification error",
+		},
+		{
+			name: "tampered payload",
+			token: func() string {
+				parts := strings.Split(f.sign(t, "k1", f.claims("client", "n1")), ".")
+				forged := signWith(t, otherKey, "k1", f.claims("attacker", "n1"))
+				parts[1] = strings.Split(forged, ".")[1]
+				return strings.Join(parts, ".")
+			},
+			nonce:   "n1",
+			wantErr: "verification error",
+		},
+		{
+			name: "symmetric algorithm",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, f.claims("client", "n1"))
+				token.Header["kid"] = "k1"
+				raw, _ := token.SignedString([]byte("secret"))
+				return raw
+			},
+			nonce:   "n1",
+			wantErr: "不支持的签名算法",
+		},
+		{
+			name: "wrong iss",
+			token: func() string {
+				c := f.claims("client", "n1")
+				c["iss"] = "https://evil.example.com"
+				return f.sign(t, "k1", c)
+			},
+			nonce:   "n1",
+			wantErr: "iss不匹配",
+		},
+		{
+			name:    "wrong aud",
+			token:   func() string { return f.sign(t, "k1", f.claims("other", "n1")) },
+			nonce:   "n1",
+			wantErr: "aud不包含当前客户端",
+		},
+		{
+			name: "multiple audiences without azp",
+			token: func() string {
+				return f.sign(t, "k1", f.claims([]string{"client", "other"}, "n1"))
+			},
+			nonce:   "n1",
+			wantErr: "azp不匹配",
+		},
+		{
+			name: "multiple audiences with wrong azp",
+			token: func() string {
+				c := f.claims([]string{"client", "other"}, "n1")
+				c["azp"] = "other"
+				return f.sign(t, "k1", c)
+			},
+			nonce:   "n1",
+			wantErr: "azp不匹配",
+		},
+		{
+			name:    "nonce mismatch",
+			token:   func() string { return f.sign(t, "k1", f.claims("client", "n1")) },
+			nonce:   "n2",
+			wantErr: "nonce不匹配",
+		},
+		{
+			name: "missing nonce",
+			token: func() string {
+				c := f.claims("client", "")
+				delete(c, "nonce")
+				return f.sign(t, "k1", c)
+			},
+			nonce:   "n1",
+			wantErr: "nonce不匹配",
+		},
+		{
+			name: "expired",
+			token: func() string {
+				c := f.claims("client", "n1")
+				c["exp"] = time.Now().Add(-time.Minute).Unix()
+				return f.sign(t, "k1", c)
+			},
+			nonce:   "n1",
+			wantErr: "expired",
+		},
+		{
+			name: "missing exp",
+			token: func() string {
+				c := f.claims("client", "n1")
+				delete(c, "exp")
+				return f.sign(t, "k1", c)
+			},
+			nonce:   "n1",
+			wantErr: "缺少exp",
+		},
+		{
+			name:    "unknown kid",
+			token:   func() string { return signWith(t, otherKey, "k9", f.claims("client", "n1")) },
+			nonce:   "n1",
+			wantErr: "未找到签名公钥",
+		},
+	}
+
+	p := f.provider(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := p.verifyIDToken(context.Background(), tt.token(), tt.nonce)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyIDToken() error = %v", err)
+				}
+				if claims["sub"] != "user-1" {
+					t.Errorf("sub = %v, want user-1", claims["sub"])
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("verifyIDToken() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRefetchesJWKSForUnknownKid(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := f.provider(t)
+
+	if _, err := p.verifyIDToken(context.Background(), f.sign(t, "k1", f.claims("client", "")), ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&f.jwksHits); n != 1 {
+		t.Fatalf("jwks fetches = %d, want 1", n)
+	}
+
+	// 提供商轮换密钥后，未知kid在最小刷新间隔内不会重复请求JWKS
+	f.addKey(t, "k2")
+	rotated := f.sign(t, "k2", f.claims("client", ""))
+	if _, err := p.verifyIDToken(context.Background(), rotated, ""); err == nil {
+		t.Fatal("verifyIDToken() succeeded before refresh interval")
+	}
+	if n := atomic.LoadInt32(&f.jwksHits); n != 1 {
+		t.Fatalf("jwks fetches = %d, want 1", n)
+	}
+
+	// 超过最小刷新间隔后，未知kid触发重新获取JWKS
+	p.jwks.mu.Lock()
+	p.jwks.fetchedAt = time.Now().Add(-jwksMinRefresh - time.Second)
+	p.jwks.mu.Unlock()
+	if _, err := p.verifyIDToken(context.Background(), rotated, ""); err != nil {
+		t.Fatalf("verifyIDToken() after rotation error = %v", err)
+	}
+	if n := atomic.LoadInt32(&f.jwksHits); n != 2 {
+		t.Fatalf("jwks fetches = %d, want 2", n)
+	}
+
+	// 已知kid直接使用缓存
+	if _, err := p.verifyIDToken(context.Background(), f.sign(t, "k1", f.claims("client", "")), ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&f.jwksHits); n != 2 {
+		t.Errorf("jwks fetches = %d, want 2", n)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     map[string]interface{}
+		wantErr string
+	}{
+		{name: "valid"},
+		{name: "issuer mismatch", doc: map[string]interface{}{"issuer": "https://evil.example.com"}, wantErr: "issuer不匹配"},
+		{name: "missing token endpoint", doc: map[string]interface{}{"token_endpoint": ""}, wantErr: "缺少必要的端点"},
+		{name: "missing jwks_uri", doc: map[string]interface{}{"jwks_uri": ""}, wantErr: "缺少必要的端点"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeIssuer(t)
+			f.doc = tt.doc
+
+			doc, err := discover(context.Background(), f.server.URL+"/")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("discover() error = %v", err)
+				}
+				if doc.TokenEndpoint != f.server.URL+"/token" || doc.JWKSURI != f.server.URL+"/jwks" {
+					t.Errorf("discover() = %+v", doc)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("discover() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCExchange(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := f.provider(t)
+
+	// 假提供商把授权码作为nonce写入ID Token
+	token, err := p.Exchange(context.Background(), "n1", AuthParams{Nonce: "n1"})
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	info, err := p.UserInfo(context.Background(), token)
+	if err != nil {
+		t.Fatalf("UserInfo() error = %v", err)
+	}
+	if info.ID != "user-1" || info.Email != "alice@example.com" || info.Username != "alice" {
+		t.Errorf("UserInfo() = %+v", info)
+	}
+
+	if _, err := p.Exchange(context.Background(), "n1", AuthParams{Nonce: "n2"}); err == nil {
+		t.Error("Exchange() with mismatched nonce succeeded")
+	}
+}
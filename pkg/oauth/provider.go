@@ -0,0 +1,125 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"go-nextjs/config"
+	"log"
+	"sync"
+)
+
+// Token 授权码换取到的令牌
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+
+	// Claims 校验通过的ID Token声明，仅OIDC提供商填充
+	Claims map[string]interface{} `json:"-"`
+}
+
+// UserInfo 统一后的第三方用户信息
+type UserInfo struct {
+	ID       string // 提供商侧的用户ID
+	Username string // 用户名
+	Nickname string // 昵称
+	Email    string // 邮箱
+	Avatar   string // 头像
+
+	EmailVerified bool // 提供商确认邮箱已验证，未提供该信息时为false
+}
+
+// AuthParams 单次登录尝试的参数，在生成授权URL和换取令牌时保持一致
+type AuthParams struct {
+	State        string // 服务端生成的state
+	CodeVerifier string // PKCE校验码
+	Nonce        string // OIDC nonce，用于绑定ID Token
+}
+
+// Provider 登录提供商
+type Provider interface {
+	// Name 提供商名称
+	Name() string
+	// AuthCodeURL 构建授权URL
+	AuthCodeURL(params AuthParams) string
+	// Exchange 使用授权码换取令牌
+	Exchange(ctx context.Context, code string, params AuthParams) (*Token, error)
+	// UserInfo 获取并映射用户信息
+	UserInfo(ctx context.Context, token *Token) (*UserInfo, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{}
+	names     []string
+)
+
+// Init 根据配置初始化登录提供商
+func Init() error {
+	mu.Lock()
+	providers = map[string]Provider{}
+	names = nil
+	mu.Unlock()
+
+	for _, cfg := range config.OAuthProviders {
+		p, err := New(cfg)
+		if err != nil {
+			return fmt.Errorf("初始化登录提供商 %s 失败: %v", cfg.Name, err)
+		}
+		Register(p)
+		log.Printf("已启用登录提供商: %s (%s)", cfg.Name, cfg.Type)
+	}
+	return nil
+}
+
+// New 根据配置创建登录提供商
+func New(cfg config.OAuthProviderConfig) (Provider, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("提供商名称为空")
+	}
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("未配置客户端ID")
+	}
+
+	switch cfg.Type {
+	case "czl_connect":
+		return newCZLConnect(cfg), nil
+	case "github":
+		return newGitHub(cfg), nil
+	case "google":
+		return newGoogle(cfg)
+	case "oidc":
+		return newOIDC(cfg)
+	}
+	return nil, fmt.Errorf("不支持的提供商类型: %s", cfg.Type)
+}
+
+// Register 注册登录提供商，同名提供商会被替换
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := providers[p.Name()]; !exists {
+		names = append(names, p.Name())
+	}
+	providers[p.Name()] = p
+}
+
+// Get 根据名称获取登录提供商
+func Get(name string) (Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Names 按配置顺序返回已启用的提供商名称
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return append([]string(nil), names...)
+}
@@ -0,0 +1,150 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"go-nextjs/config"
+	"strings"
+	"time"
+)
+
+// newCZLConnect 创建 CZL Connect 提供商
+func newCZLConnect(cfg config.OAuthProviderConfig) Provider {
+	setDefaults(&cfg,
+		"https://connect.czl.net/oauth2/authorize",
+		"https://connect.czl.net/api/oauth2/token",
+		"https://connect.czl.net/api/oauth2/userinfo",
+		"openid", "profile", "email")
+
+	return &oauth2Provider{
+		cfg: cfg,
+		mapUser: func(data map[string]interface{}) *UserInfo {
+			return &UserInfo{
+				ID:       claimString(data, "id"),
+				Username: claimString(data, "username"),
+				Nickname: claimString(data, "nickname"),
+				Email:    claimString(data, "email"),
+				Avatar:   claimString(data, "avatar"),
+
+				EmailVerified: claimBool(data, "email_verified"),
+			}
+		},
+	}
+}
+
+// gitHubProvider GitHub 提供商，邮箱及其验证状态需额外查询
+type gitHubProvider struct {
+	*oauth2Provider
+}
+
+// newGitHub 创建 GitHub 提供商
+func newGitHub(cfg config.OAuthProviderConfig) Provider {
+	setDefaults(&cfg,
+		"https://github.com/login/oauth/authorize",
+		"https://github.com/login/oauth/access_token",
+		"https://api.github.com/user",
+		"read:user", "user:email")
+
+	return &gitHubProvider{&oauth2Provider{
+		cfg: cfg,
+		mapUser: func(data map[string]interface{}) *UserInfo {
+			return &UserInfo{
+				ID:       claimString(data, "id"),
+				Username: claimString(data, "login"),
+				Nickname: claimString(data, "name"),
+				Email:    claimString(data, "email"),
+				Avatar:   claimString(data, "avatar_url"),
+			}
+		},
+	}}
+}
+
+// UserInfo 获取GitHub用户信息，邮箱为空时读取已验证的主邮箱，否则查询公开邮箱是否已验证
+func (p *gitHubProvider) UserInfo(ctx context.Context, token *Token) (*UserInfo, error) {
+	info, err := p.oauth2Provider.UserInfo(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	emailsURL := strings.TrimSuffix(p.cfg.UserinfoURL, "/user") + "/user/emails"
+	if err := getJSON(ctx, emailsURL, token.AccessToken, &emails); err != nil {
+		// 邮箱不是必需的，查询失败时忽略
+		return info, nil
+	}
+	for _, e := range emails {
+		if info.Email == "" && e.Primary && e.Verified {
+			info.Email = e.Email
+		}
+		if e.Verified && strings.EqualFold(e.Email, info.Email) {
+			info.EmailVerified = true
+			break
+		}
+	}
+	return info, nil
+}
+
+// newGoogle 创建 Google 提供商，通过发现文档获取端点并校验ID Token
+func newGoogle(cfg config.OAuthProviderConfig) (Provider, error) {
+	if cfg.Issuer == "" {
+		cfg.Issuer = "https://accounts.google.com"
+	}
+	return newOIDC(cfg)
+}
+
+// newOIDC 创建通用OIDC提供商
+//
+// 配置了issuer时通过发现文档获取端点并校验ID Token；
+// 否则需要显式配置各端点，按普通OAuth2流程读取用户信息接口。
+func newOIDC(cfg config.OAuthProviderConfig) (Provider, error) {
+	if cfg.Issuer != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		return newOIDCFromIssuer(ctx, cfg)
+	}
+
+	if cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserinfoURL == "" {
+		return nil, fmt.Errorf("通用OIDC提供商需要配置ISSUER，或同时配置AUTH_URL、TOKEN_URL和USERINFO_URL")
+	}
+	setDefaults(&cfg, "", "", "", "openid", "profile", "email")
+
+	return &oauth2Provider{cfg: cfg, mapUser: mapOIDCUser}, nil
+}
+
+// mapOIDCUser 按OIDC标准声明映射用户信息
+func mapOIDCUser(data map[string]interface{}) *UserInfo {
+	info := &UserInfo{
+		ID:       claimString(data, "sub"),
+		Username: claimString(data, "preferred_username"),
+		Nickname: claimString(data, "name"),
+		Email:    claimString(data, "email"),
+		Avatar:   claimString(data, "picture"),
+
+		EmailVerified: claimBool(data, "email_verified"),
+	}
+	// 没有用户名时使用邮箱前缀
+	if info.Username == "" && info.Email != "" {
+		info.Username, _, _ = strings.Cut(info.Email, "@")
+	}
+	return info
+}
+
+// setDefaults 为未配置的端点和授权范围填充默认值
+func setDefaults(cfg *config.OAuthProviderConfig, authURL, tokenURL, userinfoURL string, scopes ...string) {
+	if cfg.AuthURL == "" {
+		cfg.AuthURL = authURL
+	}
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = tokenURL
+	}
+	if cfg.UserinfoURL == "" {
+		cfg.UserinfoURL = userinfoURL
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = scopes
+	}
+}
@@ -0,0 +1,77 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-nextjs/config"
+)
+
+func TestMapOIDCUserEmailVerified(t *testing.T) {
+	tests := []struct {
+		claim interface{}
+		want  bool
+	}{
+		{true, true},
+		{"true", true},
+		{false, false},
+		{"false", false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		data := map[string]interface{}{"sub": "1", "email": "a@example.com"}
+		if tt.claim != nil {
+			data["email_verified"] = tt.claim
+		}
+		if got := mapOIDCUser(data).EmailVerified; got != tt.want {
+			t.Errorf("email_verified %#v: EmailVerified = %v, want %v", tt.claim, got, tt.want)
+		}
+	}
+}
+
+func TestGitHubEmailVerified(t *testing.T) {
+	type email struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	tests := []struct {
+		name         string
+		publicEmail  string
+		emails       []email
+		wantEmail    string
+		wantVerified bool
+	}{
+		{"public verified", "pub@example.com", []email{{"main@example.com", true, true}, {"pub@example.com", false, true}}, "pub@example.com", true},
+		{"public unverified", "pub@example.com", []email{{"main@example.com", true, true}, {"pub@example.com", false, false}}, "pub@example.com", false},
+		{"private primary", "", []email{{"other@example.com", false, true}, {"main@example.com", true, true}}, "main@example.com", true},
+		{"unverified primary", "", []email{{"main@example.com", true, false}}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/user":
+					json.NewEncoder(w).Encode(map[string]interface{}{"id": 42, "login": "octo", "email": tt.publicEmail})
+				case "/user/emails":
+					json.NewEncoder(w).Encode(tt.emails)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			p := newGitHub(config.OAuthProviderConfig{Name: "github", UserinfoURL: server.URL + "/user"})
+			info, err := p.UserInfo(context.Background(), &Token{AccessToken: "token"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Email != tt.wantEmail || info.EmailVerified != tt.wantVerified {
+				t.Errorf("UserInfo() = %q verified=%v, want %q verified=%v", info.Email, info.EmailVerified, tt.wantEmail, tt.wantVerified)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package spec
+
+import (
+	"go-nextjs/models"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Price 从文本中解析出的价格
+type Price struct {
+	Amount       float64 // 金额
+	Currency     string  // 货币代码，未知为空
+	BillingCycle string  // 计费周期，未知为空
+}
+
+// currencyTokens 货币符号和写法对应的货币代码
+var currencyTokens = map[string]string{
+	"$":   "USD",
+	"us$": "USD",
+	"usd": "USD",
+	"美元":  "USD",
+	"€":   "EUR",
+	"eur": "EUR",
+	"欧元":  "EUR",
+	"£":   "GBP",
+	"gbp": "GBP",
+	"英镑":  "GBP",
+	"¥":   "CNY",
+	"￥":   "CNY",
+	"cny": "CNY",
+	"rmb": "CNY",
+	"元":   "CNY",
+	"jpy": "JPY",
+	"hk$": "HKD",
+	"hkd": "HKD",
+	"cad": "CAD",
+	"aud": "AUD",
+}
+
+// 价格匹配：货币在前（"$5.99"、"USD 10"）或在后（"5.99 USD"、"99元"）
+var (
+	pricePrefixPattern = regexp.MustCompile(`(?i)(us\$|hk\$|\$|€|£|¥|￥|\b(?:usd|eur|gbp|cny|rmb|jpy|hkd|cad|aud)\b)\s*(\d+(?:\.\d+)?)`)
+	priceSuffixPattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(usd|eur|gbp|cny|rmb|jpy|hkd|cad|aud|美元|欧元|英镑|元)`)
+)
+
+// cyclePatterns 计费周期的写法，均为单词边界内的完整匹配，避免 "36 months" 被识别为 "6 months"
+var cyclePatterns = []struct {
+	cycle   string
+	pattern *regexp.Regexp
+}{
+	{models.BillingTriennially, regexp.MustCompile(`(?i)\btriennial(?:ly)?\b|/\s*3\s*(?:yrs?|years?)\b|\b(?:3|three)[\s-]*years?\b|\b36[\s-]*months?\b|/\s*3\s*年|三年`)},
+	{models.BillingBiennially, regexp.MustCompile(`(?i)\bbienn?ial(?:ly)?\b|/\s*2\s*(?:yrs?|years?)\b|\b(?:2|two)[\s-]*years?\b|\b24[\s-]*months?\b|/\s*2\s*年|[两二]年`)},
+	{models.BillingSemiannually, regexp.MustCompile(`(?i)\bsemi[\s-]?annual(?:ly)?\b|/\s*6\s*mo(?:nths?)?\b|\b(?:6|six)[\s-]*months?\b|半年`)},
+	{models.BillingQuarterly, regexp.MustCompile(`(?i)\bquarterly\b|/\s*(?:qtr|quarter)\b|\bper\s+quarter\b|/\s*3\s*mo(?:nths?)?\b|\b(?:3|three)[\s-]*months?\b|/\s*季|季付`)},
+	{models.BillingAnnually, regexp.MustCompile(`(?i)\bannual(?:ly)?\b|\byearly\b|/\s*(?:yr|year|y)\b|\b(?:per|a|one|1)\s+year\b|\b12[\s-]*months?\b|/\s*年|年付`)},
+	{models.BillingMonthly, regexp.MustCompile(`(?i)\bmonthly\b|/\s*(?:mo|month|m)\b|\b(?:per|a|one|1)\s+month\b|/\s*月|月付`)},
+}
+
+// cycleWindow 在价格后多少个字符内查找计费周期
+const cycleWindow = 24
+
+// ParsePrice 解析文本中第一个带货币的价格及其计费周期
+func ParsePrice(s string) Price {
+	var price Price
+
+	loc := pricePrefixPattern.FindStringSubmatchIndex(s)
+	suffixLoc := priceSuffixPattern.FindStringSubmatchIndex(s)
+	switch {
+	case loc != nil && (suffixLoc == nil || loc[0] <= suffixLoc[0]):
+		price.Currency = currencyTokens[strings.ToLower(s[loc[2]:loc[3]])]
+		price.Amount, _ = strconv.ParseFloat(s[loc[4]:loc[5]], 64)
+	case suffixLoc != nil:
+		loc = suffixLoc
+		price.Amount, _ = strconv.ParseFloat(s[loc[2]:loc[3]], 64)
+		price.Currency = currencyTokens[strings.ToLower(s[loc[4]:loc[5]])]
+	default:
+		return price
+	}
+
+	// 只在价格前后的一小段文本中查找离价格最近的计费周期，避免匹配到其他配置，优先使用价格之后的写法
+	after := []rune(s[loc[1]:])
+	if len(after) > cycleWindow {
+		after = after[:cycleWindow]
+	}
+	price.BillingCycle = parseCycle(string(after), false)
+	if price.BillingCycle == "" {
+		before := []rune(s[:loc[0]])
+		if len(before) > cycleWindow {
+			before = before[len(before)-cycleWindow:]
+		}
+		price.BillingCycle = parseCycle(string(before), true)
+	}
+	return price
+}
+
+// parseCycle 识别计费周期，有多个写法时取离价格最近的一个
+//
+// fromEnd为true时文本位于价格之前，取结束位置最靠后的写法，否则取开始位置最靠前的写法；
+// 位置相同时取较长的写法，如 "semi annually" 而不是 "annually"。
+func parseCycle(s string, fromEnd bool) string {
+	cycle := ""
+	bestStart, bestEnd := 0, 0
+	for _, c := range cyclePatterns {
+		for _, m := range c.pattern.FindAllStringIndex(s, -1) {
+			var better bool
+			switch {
+			case cycle == "":
+				better = true
+			case fromEnd:
+				better = m[1] > bestEnd || (m[1] == bestEnd && m[0] < bestStart)
+			default:
+				better = m[0] < bestStart || (m[0] == bestStart && m[1] > bestEnd)
+			}
+			if better {
+				cycle, bestStart, bestEnd = c.cycle, m[0], m[1]
+			}
+		}
+	}
+	return cycle
+}
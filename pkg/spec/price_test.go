@@ -0,0 +1,50 @@
+package spec
+
+import (
+	"testing"
+
+	"go-nextjs/models"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Price
+	}{
+		{"$5.99/mo", Price{5.99, "USD", models.BillingMonthly}},
+		{"$5 /mo", Price{5, "USD", models.BillingMonthly}},
+		{"$5/month", Price{5, "USD", models.BillingMonthly}},
+		{"USD 10 per month", Price{10, "USD", models.BillingMonthly}},
+		{"99元/月", Price{99, "CNY", models.BillingMonthly}},
+		{"$48/year", Price{48, "USD", models.BillingAnnually}},
+		{"$48 / year", Price{48, "USD", models.BillingAnnually}},
+		{"€20/yr", Price{20, "EUR", models.BillingAnnually}},
+		{"$30 a year", Price{30, "USD", models.BillingAnnually}},
+		{"¥199/年", Price{199, "CNY", models.BillingAnnually}},
+		{"$10 for 12 months", Price{10, "USD", models.BillingAnnually}},
+		{"$15/quarter", Price{15, "USD", models.BillingQuarterly}},
+		{"$15 for 3 months", Price{15, "USD", models.BillingQuarterly}},
+		{"$25 semi-annually", Price{25, "USD", models.BillingSemiannually}},
+		{"$25 semi annually", Price{25, "USD", models.BillingSemiannually}},
+		{"$25 for 6 months", Price{25, "USD", models.BillingSemiannually}},
+		{"$80 for 24 months", Price{80, "USD", models.BillingBiennially}},
+		{"$99/3yr", Price{99, "USD", models.BillingTriennially}},
+		// 36个月不能被识别为半年
+		{"Only $10 for 36 months", Price{10, "USD", models.BillingTriennially}},
+		// 取离价格最近的周期
+		{"$5/mo billed annually", Price{5, "USD", models.BillingMonthly}},
+		{"$60/year, that's $5 a month", Price{60, "USD", models.BillingAnnually}},
+		{"Annually: $48", Price{48, "USD", models.BillingAnnually}},
+		{"Monthly plan, billed yearly $60", Price{60, "USD", models.BillingAnnually}},
+		// 不完整的单词不算计费周期
+		{"$5 /mb", Price{5, "USD", ""}},
+		{"$5 momentum", Price{5, "USD", ""}},
+		{"$7 2GB RAM", Price{7, "USD", ""}},
+		{"no price here", Price{}},
+	}
+	for _, tt := range tests {
+		if got := ParsePrice(tt.in); got != tt.want {
+			t.Errorf("ParsePrice(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
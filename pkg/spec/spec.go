@@ -0,0 +1,273 @@
+// Package spec 将AI提取的VPS配置文本解析为数值规格
+//
+// 解析规则是确定性的，不依赖大模型的输出格式，可识别中英文常见写法。
+// 无法识别的字段保持零值。
+package spec
+
+import (
+	"go-nextjs/pkg/ai"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 硬盘类型
+const (
+	DiskNVMe = "NVMe"
+	DiskSSD  = "SSD"
+	DiskHDD  = "HDD"
+)
+
+// Specs 从VPS配置文本中解析出的数值规格
+type Specs struct {
+	Cores            int      // CPU核心数
+	RAMMB            int      // 内存大小（MB）
+	DiskGB           int      // 硬盘容量（GB）
+	DiskType         string   // 硬盘类型：NVMe、SSD、HDD，未知为空
+	TrafficGB        int      // 每月流量（GB），不限流量时为0
+	UnlimitedTraffic bool     // 是否不限流量
+	PortMbps         int      // 端口速率（Mbps）
+	IPv4             int      // IPv4数量
+	IPv6             bool     // 是否提供IPv6
+	Locations        []string // 机房位置
+}
+
+var (
+	// coresPattern 匹配 "2 Core"、"4 vCPU"、"2核" 等写法
+	coresPattern = regexp.MustCompile(`(?i)\b(\d+)\s*(?:x\s*)?(?:v?cores?|v?cpus?|核)`)
+	// shortCoresPattern 匹配 "2C"、"1C1G" 等简写，C后只能是内存或结尾，避免匹配 "EPYC 7C13" 等CPU型号
+	shortCoresPattern = regexp.MustCompile(`(?i)\b(\d+)c(?:\d+(?:\.\d+)?g|\b)`)
+	// sizePattern 匹配带单位的容量，如 "4GB"、"512 MB"、"1.5T"
+	sizePattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(TB|TiB|T|GB|GiB|G|MB|MiB|M)\b`)
+	// multiSizePattern 匹配多块硬盘的写法，如 "2x 500GB"、"2*1TB"
+	multiSizePattern = regexp.MustCompile(`(?i)(\d+)\s*[x×*]\s*(\d+(?:\.\d+)?)\s*(TB|TiB|T|GB|GiB|G|MB|MiB|M)\b`)
+	// portPattern 匹配端口速率，如 "1Gbps"、"100 Mbps"、"10G port"、"200Mbit/s"
+	portPattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(G|M)(?:bps|bit(?:/s)?|b/s|\s*port|\s*端口|\s*带宽)`)
+	// ipv4CountPattern 匹配 "2 IPv4"、"3x IPv4"、"1个IPv4"
+	ipv4CountPattern = regexp.MustCompile(`(?i)(\d+)\s*(?:[x×*]\s*)?(?:个\s*)?(?:dedicated\s+|独立\s*)?ipv4`)
+	// ipv4SuffixPattern 匹配 "IPv4 x 2"、"IPv4: 3"
+	ipv4SuffixPattern = regexp.MustCompile(`(?i)ipv4\s*(?:[x×*:：]\s*)(\d+)`)
+	// natIPv4Pattern 匹配NAT或共享的IPv4，如 "NAT IPv4"、"IPv4 (NAT)"、"共享IPv4"，不计入独立IPv4
+	natIPv4Pattern = regexp.MustCompile(`(?i)(?:\d+\s*(?:[x×*]\s*)?(?:个\s*)?)?(?:nat|shared|共享)\s*-?\s*ipv4(?:\s*[x×*:：]\s*\d+)?|ipv4\s*[(（]?\s*(?:nat|shared|共享)\s*[)）]?`)
+	// locationSeparator 位置列表的分隔符，逗号另行处理
+	locationSeparator = regexp.MustCompile(`(?i)\s*(?:[、;；/|+&]|\band\b|\bor\b|或|和)\s*`)
+	// commaSeparator 逗号，可能分隔位置，也可能是 "城市, 地区" 的写法
+	commaSeparator = regexp.MustCompile(`\s*[,，]\s*`)
+	// regionCodePattern 地区或国家代码，如 CA、JP、USA
+	regionCodePattern = regexp.MustCompile(`^[A-Z]{2,3}$`)
+	// numberPattern 匹配第一个数字
+	numberPattern = regexp.MustCompile(`\d+`)
+)
+
+// Parse 解析AI提取的VPS配置
+func Parse(cfg ai.VPSConfig) Specs {
+	diskGB, diskType := ParseDisk(cfg.Disk)
+	trafficGB, unlimited, portMbps := ParseBandwidth(cfg.Bandwidth)
+	ipv4, ipv6 := ParseIP(cfg.IP)
+
+	return Specs{
+		Cores:            ParseCores(cfg.CPU),
+		RAMMB:            ParseSizeMB(cfg.RAM),
+		DiskGB:           diskGB,
+		DiskType:         diskType,
+		TrafficGB:        trafficGB,
+		UnlimitedTraffic: unlimited,
+		PortMbps:         portMbps,
+		IPv4:             ipv4,
+		IPv6:             ipv6,
+		Locations:        ParseLocations(cfg.Location),
+	}
+}
+
+// ParseCores 解析CPU核心数
+func ParseCores(s string) int {
+	for _, pattern := range []*regexp.Regexp{coresPattern, shortCoresPattern} {
+		if m := pattern.FindStringSubmatch(s); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			return n
+		}
+	}
+	// 只有数字时视为核心数
+	if m := numberPattern.FindString(s); m != "" && strings.TrimSpace(s) == m {
+		n, _ := strconv.Atoi(m)
+		return n
+	}
+	return 0
+}
+
+// ParseSizeMB 解析带单位的容量，统一换算为MB
+func ParseSizeMB(s string) int {
+	m := sizePattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+	return toMB(m[1], m[2])
+}
+
+// ParseDisk 解析硬盘容量（GB）和类型，支持 "2x 500GB" 这类多块硬盘的写法
+func ParseDisk(s string) (int, string) {
+	var mb int
+	if m := multiSizePattern.FindStringSubmatch(s); m != nil {
+		count, _ := strconv.Atoi(m[1])
+		mb = count * toMB(m[2], m[3])
+	} else {
+		mb = ParseSizeMB(s)
+	}
+
+	lower := strings.ToLower(s)
+	diskType := ""
+	switch {
+	case strings.Contains(lower, "nvme"):
+		diskType = DiskNVMe
+	case strings.Contains(lower, "ssd"), strings.Contains(lower, "固态"):
+		diskType = DiskSSD
+	case strings.Contains(lower, "hdd"), strings.Contains(lower, "sata"), strings.Contains(lower, "机械"):
+		diskType = DiskHDD
+	}
+
+	return mb / 1024, diskType
+}
+
+// ParseBandwidth 解析带宽描述，返回每月流量（GB）、是否不限流量和端口速率（Mbps）
+func ParseBandwidth(s string) (int, bool, int) {
+	portMbps := 0
+	if m := portPattern.FindStringSubmatch(s); m != nil {
+		value, _ := strconv.ParseFloat(m[1], 64)
+		if strings.EqualFold(m[2], "G") {
+			value *= 1000
+		}
+		portMbps = int(value + 0.5)
+	}
+
+	lower := strings.ToLower(s)
+	for _, keyword := range []string{"unlimited", "unmetered", "不限", "无限"} {
+		if strings.Contains(lower, keyword) {
+			return 0, true, portMbps
+		}
+	}
+
+	// 去掉端口速率后剩下的容量即为流量
+	rest := portPattern.ReplaceAllString(s, " ")
+	return ParseSizeMB(rest) / 1024, false, portMbps
+}
+
+// ParseIP 解析IP描述，返回IPv4数量和是否提供IPv6
+func ParseIP(s string) (int, bool) {
+	lower := strings.ToLower(s)
+	// NAT或共享的IPv4不是独立IP，先去掉再统计
+	s = natIPv4Pattern.ReplaceAllString(s, " ")
+	lowerV4 := strings.ToLower(s)
+
+	ipv6 := strings.Contains(lower, "ipv6") &&
+		!containsAny(lower, "no ipv6", "without ipv6", "无ipv6", "不提供ipv6", "不支持ipv6")
+
+	ipv4 := 0
+	switch {
+	case containsAny(lower, "ipv6 only", "ipv6-only", "only ipv6", "仅ipv6", "纯ipv6", "no ipv4", "无ipv4"):
+		ipv4 = 0
+	case ipv4CountPattern.MatchString(s):
+		ipv4, _ = strconv.Atoi(ipv4CountPattern.FindStringSubmatch(s)[1])
+	case ipv4SuffixPattern.MatchString(s):
+		ipv4, _ = strconv.Atoi(ipv4SuffixPattern.FindStringSubmatch(s)[1])
+	case strings.Contains(lowerV4, "ipv4"):
+		// 提到IPv4但没有数量时视为1个
+		ipv4 = 1
+	}
+
+	return ipv4, ipv6
+}
+
+// ParseLocations 将位置描述拆分为去重后的位置列表
+//
+// 逗号既用于分隔多个位置，也用于 "Los Angeles, CA"、"Frankfurt, Germany" 这类
+// "城市, 地区" 的写法：逗号后是地区代码或国家、州名，且前面不是国家时保留为一个位置。
+func ParseLocations(s string) []string {
+	var locations []string
+	seen := make(map[string]bool)
+	add := func(location string) {
+		key := strings.ToLower(location)
+		if location == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		locations = append(locations, location)
+	}
+
+	for _, group := range locationSeparator.Split(s, -1) {
+		current, qualified := "", false
+		for _, part := range commaSeparator.Split(strings.TrimSpace(group), -1) {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			// 每个位置最多带一个地区限定
+			if current != "" && !qualified && isRegionQualifier(part) && !isCountry(current) {
+				current += ", " + part
+				qualified = true
+				continue
+			}
+			add(current)
+			current, qualified = part, false
+		}
+		add(current)
+	}
+	return locations
+}
+
+// isRegionQualifier 判断逗号后的部分是否为地区限定，如 "CA"、"JP"、"Germany"、"California"
+func isRegionQualifier(part string) bool {
+	lower := strings.ToLower(part)
+	return regionCodePattern.MatchString(part) || countryNames[lower] || stateNames[lower]
+}
+
+// isCountry 判断是否为国家名或代码，国家后面的逗号分隔的是另一个位置
+func isCountry(part string) bool {
+	return regionCodePattern.MatchString(part) || countryNames[strings.ToLower(part)]
+}
+
+// countryNames 常见的国家名
+var countryNames = map[string]bool{
+	"usa": true, "united states": true, "canada": true, "mexico": true, "brazil": true,
+	"germany": true, "france": true, "netherlands": true, "the netherlands": true, "united kingdom": true,
+	"england": true, "ireland": true, "spain": true, "italy": true, "poland": true, "sweden": true,
+	"finland": true, "norway": true, "switzerland": true, "austria": true, "romania": true,
+	"bulgaria": true, "russia": true, "turkey": true, "japan": true, "korea": true, "south korea": true,
+	"china": true, "taiwan": true, "vietnam": true, "thailand": true, "malaysia": true,
+	"indonesia": true, "philippines": true, "india": true, "australia": true, "new zealand": true,
+	"south africa": true, "israel": true, "uae": true,
+}
+
+// stateNames 常见的美国州和加拿大省名
+var stateNames = map[string]bool{
+	"california": true, "texas": true, "new york": true, "new jersey": true, "virginia": true,
+	"washington": true, "oregon": true, "nevada": true, "arizona": true, "utah": true,
+	"colorado": true, "illinois": true, "georgia": true, "florida": true, "ohio": true,
+	"missouri": true, "kansas": true, "north carolina": true, "ontario": true, "quebec": true,
+	"british columbia": true,
+}
+
+// toMB 将数值和单位换算为MB
+func toMB(number string, unit string) int {
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0
+	}
+
+	switch strings.ToUpper(unit[:1]) {
+	case "T":
+		value *= 1024 * 1024
+	case "G":
+		value *= 1024
+	}
+	return int(value + 0.5)
+}
+
+// containsAny 判断字符串是否包含任一关键字
+func containsAny(s string, keywords ...string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
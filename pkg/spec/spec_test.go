@@ -0,0 +1,232 @@
+package spec
+
+import (
+	"reflect"
+	"testing"
+
+	"go-nextjs/pkg/ai"
+)
+
+func TestParseCores(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"2 Core", 2},
+		{"1 Core", 1},
+		{"4 vCPU", 4},
+		{"8 vCPUs", 8},
+		{"2 CPU", 2},
+		{"3 Cores", 3},
+		{"6 vCores", 6},
+		{"2核", 2},
+		{"4 核", 4},
+		{"2C", 2},
+		{"2C4G", 2},
+		{"1C1G", 1},
+		{"4c8g", 4},
+		{"2x vCPU", 2},
+		{"2 x Core", 2},
+		{"AMD EPYC 2 Core", 2},
+		{"Intel Xeon E5-2680 v4, 4 vCPU", 4},
+		{"Ryzen 9 7950X 1 Core", 1},
+		// CPU型号中的数字和C不是核心数
+		{"AMD EPYC 7C13 2 vCore", 2},
+		{"AMD EPYC 7C13, 2C2G", 2},
+		{"AMD EPYC 7C13", 0},
+		{"Intel Xeon E5-2680v4 4 vCPU", 4},
+		{"Intel Xeon E5-2680v4 CPU", 0},
+		{"Xeon Gold 6148 x 2核", 2},
+		{"4", 4},
+		{" 16 ", 16},
+		{"", 0},
+		{"Shared CPU", 0},
+		{"Intel Xeon", 0},
+	}
+	for _, tt := range tests {
+		if got := ParseCores(tt.in); got != tt.want {
+			t.Errorf("ParseCores(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseSizeMB(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"4GB RAM", 4096},
+		{"4 GB", 4096},
+		{"4G", 4096},
+		{"1C1G", 1024},
+		{"512MB RAM", 512},
+		{"512 MB", 512},
+		{"768M", 768},
+		{"1.5GB", 1536},
+		{"2GiB", 2048},
+		{"1TB", 1024 * 1024},
+		{"1T", 1024 * 1024},
+		{"256MiB", 256},
+		{"内存 8GB", 8192},
+		{"", 0},
+		{"unknown", 0},
+	}
+	for _, tt := range tests {
+		if got := ParseSizeMB(tt.in); got != tt.want {
+			t.Errorf("ParseSizeMB(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDisk(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantGB   int
+		wantType string
+	}{
+		{"50GB SSD", 50, DiskSSD},
+		{"20 GB NVMe", 20, DiskNVMe},
+		{"1TB HDD", 1024, DiskHDD},
+		{"2x 500GB SSD", 1000, DiskSSD},
+		{"2*1TB HDD", 2048, DiskHDD},
+		{"2 × 960GB NVMe SSD", 1920, DiskNVMe},
+		{"500GB SATA", 500, DiskHDD},
+		{"40G 固态", 40, DiskSSD},
+		{"2T 机械硬盘", 2048, DiskHDD},
+		{"100GB", 100, ""},
+		{"512MB", 0, ""},
+		{"", 0, ""},
+	}
+	for _, tt := range tests {
+		gotGB, gotType := ParseDisk(tt.in)
+		if gotGB != tt.wantGB || gotType != tt.wantType {
+			t.Errorf("ParseDisk(%q) = %d, %q, want %d, %q", tt.in, gotGB, gotType, tt.wantGB, tt.wantType)
+		}
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		in            string
+		wantTraffic   int
+		wantUnlimited bool
+		wantPort      int
+	}{
+		{"500GB Traffic@1Gbps port", 500, false, 1000},
+		{"1TB Traffic", 1024, false, 0},
+		{"2TB/月 @ 200Mbps", 2048, false, 200},
+		{"Unlimited Traffic@1Gbps", 0, true, 1000},
+		{"Unmetered 10Gbps", 0, true, 10000},
+		{"Unlimited Traffic", 0, true, 0},
+		{"不限流量 100M带宽", 0, true, 100},
+		{"无限流量@1G端口", 0, true, 1000},
+		{"3000GB @ 10G port", 3000, false, 10000},
+		{"200Mbit/s", 0, false, 200},
+		{"1000GB 流量 500Mb/s", 1000, false, 500},
+		{"2.5Gbps", 0, false, 2500},
+		{"", 0, false, 0},
+	}
+	for _, tt := range tests {
+		traffic, unlimited, port := ParseBandwidth(tt.in)
+		if traffic != tt.wantTraffic || unlimited != tt.wantUnlimited || port != tt.wantPort {
+			t.Errorf("ParseBandwidth(%q) = %d, %v, %d, want %d, %v, %d",
+				tt.in, traffic, unlimited, port, tt.wantTraffic, tt.wantUnlimited, tt.wantPort)
+		}
+	}
+}
+
+func TestParseIP(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantIPv4 int
+		wantIPv6 bool
+	}{
+		{"1 IPv4", 1, false},
+		{"2 IPv4", 2, false},
+		{"IPv4 + IPv6", 1, true},
+		{"2 IPv4 + IPv6", 2, true},
+		{"3x IPv4", 3, false},
+		{"1个IPv4", 1, false},
+		{"1 专用IPv4 + IPv6 子网", 1, true},
+		{"1 Dedicated IPv4", 1, false},
+		{"IPv4 x 2", 2, false},
+		{"IPv4: 3", 3, false},
+		{"/64 IPv6", 0, true},
+		{"IPv6 only", 0, true},
+		{"仅IPv6", 0, true},
+		{"NAT IPv4", 0, false},
+		{"NAT IPv4 + IPv6", 0, true},
+		{"1 NAT IPv4 + /64 IPv6", 0, true},
+		{"IPv4 (NAT) + IPv6", 0, true},
+		{"共享IPv4 + IPv6", 0, true},
+		{"Shared IPv4", 0, false},
+		{"1 IPv4 + NAT IPv4", 1, false},
+		{"1 IPv4, no IPv6", 1, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		ipv4, ipv6 := ParseIP(tt.in)
+		if ipv4 != tt.wantIPv4 || ipv6 != tt.wantIPv6 {
+			t.Errorf("ParseIP(%q) = %d, %v, want %d, %v", tt.in, ipv4, ipv6, tt.wantIPv4, tt.wantIPv6)
+		}
+	}
+}
+
+func TestParseLocations(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Los Angeles", []string{"Los Angeles"}},
+		{"Los Angeles, CA", []string{"Los Angeles, CA"}},
+		{"Frankfurt, Germany", []string{"Frankfurt, Germany"}},
+		{"Tokyo, JP", []string{"Tokyo, JP"}},
+		{"Tokyo, JP, Hanoi, VN", []string{"Tokyo, JP", "Hanoi, VN"}},
+		{"Singapore DC1, Hong Kong DC2, Tokyo JP, Hanoi VN", []string{"Singapore DC1", "Hong Kong DC2", "Tokyo JP", "Hanoi VN"}},
+		{"Los Angeles, CA, New York, NY", []string{"Los Angeles, CA", "New York, NY"}},
+		{"San Jose, California", []string{"San Jose, California"}},
+		{"Japan, Germany", []string{"Japan", "Germany"}},
+		{"NYC, LA", []string{"NYC", "LA"}},
+		{"Los Angeles / San Jose", []string{"Los Angeles", "San Jose"}},
+		{"Dallas and Miami", []string{"Dallas", "Miami"}},
+		{"Dallas AND Miami", []string{"Dallas", "Miami"}},
+		{"Dallas OR Miami", []string{"Dallas", "Miami"}},
+		{"Amsterdam | London", []string{"Amsterdam", "London"}},
+		{"香港、东京、新加坡", []string{"香港", "东京", "新加坡"}},
+		{"香港，东京", []string{"香港", "东京"}},
+		{"洛杉矶或圣何塞", []string{"洛杉矶", "圣何塞"}},
+		{"Los Angeles; los angeles", []string{"Los Angeles"}},
+		{"Orlando", []string{"Orlando"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := ParseLocations(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLocations(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	got := Parse(ai.VPSConfig{
+		CPU:       "2 Core",
+		RAM:       "4GB RAM",
+		Disk:      "2x 50GB NVMe",
+		Bandwidth: "1TB Traffic@1Gbps port",
+		IP:        "1 IPv4 + IPv6",
+		Location:  "Los Angeles, CA, Tokyo, JP",
+	})
+	want := Specs{
+		Cores:     2,
+		RAMMB:     4096,
+		DiskGB:    100,
+		DiskType:  DiskNVMe,
+		TrafficGB: 1024,
+		PortMbps:  1000,
+		IPv4:      1,
+		IPv6:      true,
+		Locations: []string{"Los Angeles, CA", "Tokyo, JP"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+}
@@ -14,29 +14,76 @@ func SetupRouter() *gin.Engine {
 	// 添加CORS中间件
 	r.Use(middleware.CORSMiddleware())
 
+	// 公开的JWT验证公钥，供其他服务校验访问令牌
+	r.GET("/.well-known/jwks.json", handler.JWKS)
+
 	// 认证相关路由
 	auth := r.Group("/api/auth")
 	{
+		auth.GET("/providers", handler.ListProviders)
+		auth.GET("/:provider/login", handler.Login)
+		auth.GET("/:provider/callback", handler.Callback)
+		// 兼容旧版路由，默认使用 CZL Connect
 		auth.GET("/login", handler.Login)
 		auth.GET("/callback", handler.Callback)
+		auth.POST("/exchange", handler.Exchange)
+		auth.POST("/refresh", handler.Refresh)
 		auth.GET("/me", middleware.AuthRequired(), handler.GetCurrentUser)
-		auth.GET("/logout", handler.Logout)
+		auth.POST("/logout", middleware.AuthRequired(), handler.Logout)
+		auth.POST("/logout-all", middleware.AuthRequired(), handler.LogoutAll)
+		auth.GET("/sessions", middleware.AuthRequired(), handler.ListMySessions)
+		auth.DELETE("/sessions/:id", middleware.AuthRequired(), handler.RevokeMySession)
 	}
 
 	// 公开路由
 	public := r.Group("/api")
 	{
-		//返回ok
-		public.GET("/deals", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "ok"})
-		})
+		// 优惠信息
+		public.GET("/deals", handler.ListDeals)
+		public.GET("/deals/:id", handler.GetDeal)
+		public.GET("/deals/:id/prices", handler.ListDealPrices)
+		public.GET("/deal-events", handler.ListDealEvents)
+		public.GET("/exchange-rates", handler.ListExchangeRates)
 	}
 
-	// 需要认证的路由 - 只需要验证用户身份，不再检查admin角色
+	// 管理员路由 - 需要登录且角色为admin
 	admin := r.Group("/api")
-	admin.Use(middleware.AuthRequired())
+	admin.Use(middleware.AuthRequired(), middleware.AdminRequired())
 	{
+		// 优惠管理
+		admin.POST("/deals", handler.CreateDeal)
+		admin.PUT("/deals/:id", handler.UpdateDeal)
+		admin.DELETE("/deals/:id", handler.DeleteDeal)
+		admin.POST("/deals/:id/merge", handler.MergeDeals)
+		admin.POST("/deals/:id/split", handler.SplitDeals)
+		admin.GET("/deals/:id/merge-logs", handler.ListDealMergeLogs)
+
+		// 数据来源管理
+		admin.GET("/sources", handler.ListSources)
+		admin.POST("/sources", handler.CreateSource)
+		admin.PUT("/sources/:id", handler.UpdateSource)
+		admin.DELETE("/sources/:id", handler.DeleteSource)
+		admin.POST("/sources/:id/sync", handler.SyncSource)
+		admin.POST("/sources/preview", handler.PreviewSource)
+		admin.POST("/sources/:id/preview", handler.PreviewStoredSource)
+
+		// 汇率管理
+		admin.PUT("/exchange-rates/:currency", handler.SetExchangeRate)
+		admin.DELETE("/exchange-rates/:currency", handler.DeleteExchangeRate)
+		admin.POST("/exchange-rates/import", handler.ImportExchangeRates)
+
+		// AI缓存管理
+		admin.GET("/ai-cache", handler.GetAICacheStats)
+		admin.DELETE("/ai-cache", handler.PurgeAICache)
+
+		// 用户管理
+		admin.GET("/users", handler.ListUsers)
+		admin.PUT("/users/:id/role", handler.UpdateUserRole)
 
+		// 会话管理
+		admin.GET("/users/:id/sessions", handler.ListUserSessions)
+		admin.DELETE("/users/:id/sessions", handler.RevokeUserSessions)
+		admin.DELETE("/sessions/:id", handler.RevokeAnySession)
 	}
 
 	return r
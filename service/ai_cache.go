@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"go-nextjs/pkg/ai"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 进程启动以来的缓存命中和未命中次数
+var aiCacheHits, aiCacheMisses atomic.Int64
+
+// AICacheStats AI缓存的统计信息
+type AICacheStats struct {
+	Entries    int64            `json:"entries"`     // 有效的条目数
+	Expired    int64            `json:"expired"`     // 已过期待清理的条目数
+	ByKind     map[string]int64 `json:"by_kind"`     // 各类型的有效条目数
+	MaxEntries int              `json:"max_entries"` // 条目数上限
+	TTLSeconds int64            `json:"ttl_seconds"` // 有效期（秒）
+	Hits       int64            `json:"hits"`        // 启动以来的命中次数
+	Misses     int64            `json:"misses"`      // 启动以来的未命中次数
+	HitRate    float64          `json:"hit_rate"`    // 命中率
+}
+
+// dbAICache 保存在数据库中的AI结果缓存
+type dbAICache struct{}
+
+// InitAICache 为AI调用启用数据库缓存，AI_CACHE_MAX_ENTRIES为0时不启用
+func InitAICache() {
+	if config.AICacheMaxEntries <= 0 {
+		return
+	}
+	ai.SetDefaultCache(dbAICache{})
+}
+
+// Get 读取未过期的缓存
+func (dbAICache) Get(ctx context.Context, key string) ([]byte, bool) {
+	var entries []models.AICache
+	err := config.DB.WithContext(ctx).
+		Where("key = ? AND expires_at > ?", key, time.Now()).
+		Limit(1).Find(&entries).Error
+	if err != nil || len(entries) == 0 {
+		aiCacheMisses.Add(1)
+		return nil, false
+	}
+
+	aiCacheHits.Add(1)
+	config.DB.Model(&entries[0]).UpdateColumns(map[string]interface{}{
+		"hit_count":    gorm.Expr("hit_count + 1"),
+		"last_used_at": time.Now(),
+	})
+	return []byte(entries[0].Value), true
+}
+
+// Set 写入缓存，已存在时覆盖并重新计算过期时间，新增条目超出条数上限时删除最久未使用的条目
+func (dbAICache) Set(ctx context.Context, entry *ai.CacheEntry) {
+	var entries []models.AICache
+	if err := config.DB.Where("key = ?", entry.Key).Limit(1).Find(&entries).Error; err != nil {
+		log.Printf("写入AI缓存失败: %v", err)
+		return
+	}
+
+	item := models.AICache{Key: entry.Key}
+	if len(entries) > 0 {
+		item = entries[0]
+	}
+	item.Kind = entry.Kind
+	item.Model = entry.Model
+	item.Value = string(entry.Value)
+	item.LastUsedAt = time.Now()
+	item.ExpiresAt = item.LastUsedAt.Add(config.AICacheTTL)
+	if err := config.DB.Save(&item).Error; err != nil {
+		log.Printf("写入AI缓存失败: %v", err)
+		return
+	}
+	if len(entries) == 0 {
+		if _, err := evictAICache(); err != nil {
+			log.Printf("清理超出上限的AI缓存失败: %v", err)
+		}
+	}
+}
+
+// GetAICacheStats 获取AI缓存的统计信息
+func GetAICacheStats() (*AICacheStats, error) {
+	now := time.Now()
+	stats := &AICacheStats{
+		ByKind:     make(map[string]int64),
+		MaxEntries: config.AICacheMaxEntries,
+		TTLSeconds: int64(config.AICacheTTL / time.Second),
+		Hits:       aiCacheHits.Load(),
+		Misses:     aiCacheMisses.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+
+	var kinds []struct {
+		Kind  string
+		Count int64
+	}
+	err := config.DB.Model(&models.AICache{}).
+		Select("kind, COUNT(*) AS count").
+		Where("expires_at > ?", now).
+		Group("kind").Scan(&kinds).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range kinds {
+		stats.ByKind[k.Kind] = k.Count
+		stats.Entries += k.Count
+	}
+
+	err = config.DB.Model(&models.AICache{}).Where("expires_at <= ?", now).Count(&stats.Expired).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// PurgeAICache 清空AI缓存，kind不为空时只清空该类型，返回删除的条目数
+func PurgeAICache(kind string) (int64, error) {
+	db := config.DB.Session(&gorm.Session{AllowGlobalUpdate: true})
+	if kind != "" {
+		db = db.Where("kind = ?", kind)
+	}
+	result := db.Delete(&models.AICache{})
+	return result.RowsAffected, result.Error
+}
+
+// CleanupAICache 删除过期的缓存，超过条数上限时删除最久未使用的条目
+func CleanupAICache() (int64, error) {
+	result := config.DB.Where("expires_at <= ?", time.Now()).Delete(&models.AICache{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	evicted, err := evictAICache()
+	return result.RowsAffected + evicted, err
+}
+
+// evictAICache 条目数超过上限时删除最久未使用的条目，返回删除的条目数
+func evictAICache() (int64, error) {
+	var count int64
+	if err := config.DB.Model(&models.AICache{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	excess := count - int64(config.AICacheMaxEntries)
+	if excess <= 0 {
+		return 0, nil
+	}
+	oldest := config.DB.Model(&models.AICache{}).Select("id").Order("last_used_at, id").Limit(int(excess))
+	result := config.DB.Where("id IN (?)", oldest).Delete(&models.AICache{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"go-nextjs/pkg/ai"
+)
+
+func setupAICache(t *testing.T, maxEntries int) {
+	t.Helper()
+	setupTestDB(t)
+	config.AICacheMaxEntries = maxEntries
+	config.AICacheTTL = time.Hour
+}
+
+func cachedKeys(t *testing.T) map[string]bool {
+	t.Helper()
+	var entries []models.AICache
+	if err := config.DB.Find(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	keys := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		keys[e.Key] = true
+	}
+	return keys
+}
+
+func setCache(key string) {
+	dbAICache{}.Set(context.Background(), &ai.CacheEntry{Key: key, Kind: ai.CacheKindTitle, Model: "test", Value: []byte(key)})
+}
+
+func TestAICacheEvictsLeastRecentlyUsedOnInsert(t *testing.T) {
+	setupAICache(t, 3)
+	ctx := context.Background()
+	cache := dbAICache{}
+
+	setCache("a")
+	setCache("b")
+	setCache("c")
+
+	// 命中a后，最久未使用的是b
+	if value, ok := cache.Get(ctx, "a"); !ok || string(value) != "a" {
+		t.Fatalf("Get(a) = %q, %v", value, ok)
+	}
+	setCache("d")
+	if keys := cachedKeys(t); len(keys) != 3 || keys["b"] || !keys["a"] || !keys["c"] || !keys["d"] {
+		t.Fatalf("keys after insert = %v, want a, c, d", keys)
+	}
+
+	// 覆盖已有条目不触发删除，并刷新使用时间
+	setCache("c")
+	setCache("e")
+	if keys := cachedKeys(t); len(keys) != 3 || keys["a"] || !keys["c"] || !keys["d"] || !keys["e"] {
+		t.Fatalf("keys after overwrite = %v, want c, d, e", keys)
+	}
+
+	var entry models.AICache
+	config.DB.Where("key = ?", "c").First(&entry)
+	if entry.LastUsedAt.IsZero() {
+		t.Error("last_used_at not set")
+	}
+}
+
+func TestCleanupAICache(t *testing.T) {
+	setupAICache(t, 10)
+	setCache("a")
+	setCache("b")
+	config.DB.Model(&models.AICache{}).Where("key = ?", "a").Update("expires_at", time.Now().Add(-time.Minute))
+
+	if _, ok := (dbAICache{}).Get(context.Background(), "a"); ok {
+		t.Error("Get() returned expired entry")
+	}
+	deleted, err := CleanupAICache()
+	if err != nil || deleted != 1 {
+		t.Fatalf("CleanupAICache() = %d, %v, want 1", deleted, err)
+	}
+	if keys := cachedKeys(t); len(keys) != 1 || !keys["b"] {
+		t.Errorf("keys = %v, want b", keys)
+	}
+}
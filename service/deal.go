@@ -0,0 +1,273 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"go-nextjs/pkg/ai"
+	"go-nextjs/pkg/spec"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DealInput 创建或更新优惠信息的参数
+type DealInput struct {
+	Title        string  `json:"title" binding:"required"`
+	Provider     string  `json:"provider"`
+	Price        float64 `json:"price"`
+	Currency     string  `json:"currency"`
+	BillingCycle string  `json:"billing_cycle"`
+	URL          string  `json:"url"`
+	ai.VPSConfig
+
+	Status string     `json:"status"`  // 状态，为空时保持不变，新建时为active
+	EndsAt *time.Time `json:"ends_at"` // 结束时间，为空时保持不变
+}
+
+// GetDeal 根据ID获取优惠
+func GetDeal(id uint) (*models.Deal, error) {
+	var deal models.Deal
+	if err := config.DB.First(&deal, id).Error; err != nil {
+		return nil, err
+	}
+	return &deal, nil
+}
+
+// CreateDeal 创建优惠
+func CreateDeal(input *DealInput) (*models.Deal, error) {
+	deal := &models.Deal{}
+	if err := applyDealInput(deal, input); err != nil {
+		return nil, err
+	}
+	if err := saveDeal(deal, nil); err != nil {
+		return nil, err
+	}
+	return deal, nil
+}
+
+// UpdateDeal 更新优惠
+func UpdateDeal(id uint, input *DealInput) (*models.Deal, error) {
+	deal, err := GetDeal(id)
+	if err != nil {
+		return nil, err
+	}
+	old := *deal
+	if err := applyDealInput(deal, input); err != nil {
+		return nil, err
+	}
+	if err := saveDeal(deal, &old); err != nil {
+		return nil, err
+	}
+	return deal, nil
+}
+
+// DeleteDeal 删除优惠，合并到该优惠的重复记录由最早的一条接替为主优惠
+func DeleteDeal(id uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Deal{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return promoteDuplicate(tx, id)
+	})
+}
+
+// ApplyVPSConfig 将AI提取的配置写入优惠
+func ApplyVPSConfig(deal *models.Deal, cfg *ai.VPSConfig) {
+	if cfg == nil {
+		return
+	}
+	deal.CPU = cfg.CPU
+	deal.RAM = cfg.RAM
+	deal.Disk = cfg.Disk
+	deal.Bandwidth = cfg.Bandwidth
+	deal.IP = cfg.IP
+	deal.Location = cfg.Location
+	deal.Remark = cfg.Remark
+}
+
+// applyDealInput 校验参数并写入优惠
+func applyDealInput(deal *models.Deal, input *DealInput) error {
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		return fmt.Errorf("%w: 标题不能为空", ErrInvalidInput)
+	}
+	if input.Price < 0 {
+		return fmt.Errorf("%w: 价格不能为负数", ErrInvalidInput)
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(input.Currency))
+	if currency == "" {
+		currency = "USD"
+	}
+	cycle := strings.ToLower(strings.TrimSpace(input.BillingCycle))
+	if cycle == "" {
+		cycle = models.BillingMonthly
+	}
+	if _, ok := models.BillingCycleMonths[cycle]; !ok {
+		return fmt.Errorf("%w: 无效的计费周期 %s", ErrInvalidInput, input.BillingCycle)
+	}
+	status := strings.ToLower(strings.TrimSpace(input.Status))
+	if status != "" && !isDealStatus(status) {
+		return fmt.Errorf("%w: 无效的状态 %s", ErrInvalidInput, input.Status)
+	}
+
+	deal.Title = title
+	deal.Provider = strings.TrimSpace(input.Provider)
+	deal.Price = input.Price
+	deal.Currency = currency
+	deal.BillingCycle = cycle
+	deal.URL = strings.TrimSpace(input.URL)
+	if status != "" {
+		deal.Status = status
+	} else if deal.Status == "" {
+		deal.Status = models.DealStatusActive
+	}
+	if input.EndsAt != nil {
+		deal.EndsAt = input.EndsAt
+	}
+	ApplyVPSConfig(deal, &input.VPSConfig)
+	computeDealMetrics(deal)
+	return nil
+}
+
+// isDealStatus 判断是否为有效的优惠状态
+func isDealStatus(status string) bool {
+	for _, s := range models.DealStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// computeDealMetrics 根据配置文本和价格计算用于筛选排序的数值字段
+func computeDealMetrics(deal *models.Deal) {
+	specs := spec.Parse(ai.VPSConfig{
+		CPU:       deal.CPU,
+		RAM:       deal.RAM,
+		Disk:      deal.Disk,
+		Bandwidth: deal.Bandwidth,
+		IP:        deal.IP,
+		Location:  deal.Location,
+	})
+	deal.CPUCores = specs.Cores
+	deal.RAMMB = specs.RAMMB
+	deal.DiskGB = specs.DiskGB
+	deal.DiskType = specs.DiskType
+	deal.TrafficGB = specs.TrafficGB
+	deal.Unlimited = specs.UnlimitedTraffic
+	deal.PortMbps = specs.PortMbps
+	deal.IPv4Count = specs.IPv4
+	deal.IPv6 = specs.IPv6
+
+	months := models.BillingCycleMonths[deal.BillingCycle]
+	if months <= 0 {
+		months = 1
+	}
+	deal.MonthlyPrice = deal.Price / float64(months)
+	computeBasePrice(deal)
+
+	deal.Fingerprint = dealFingerprint(deal, specs.Locations)
+}
+
+// computeBasePrice 将月付价格按汇率折算为基准货币
+func computeBasePrice(deal *models.Deal) {
+	deal.BaseMonthlyPrice = nil
+	deal.PricePerGBRAM = 0
+
+	price, ok := toBaseCurrency(deal.MonthlyPrice, deal.Currency)
+	if !ok {
+		return
+	}
+	deal.BaseMonthlyPrice = &price
+	if deal.RAMMB > 0 {
+		deal.PricePerGBRAM = price / (float64(deal.RAMMB) / 1024)
+	}
+}
+
+// dealFingerprint 根据服务商、规格、月付价格和首个位置计算指纹，用于识别不同来源中的同一优惠
+//
+// 标题不参与计算，不同论坛对同一优惠的描述往往不同。缺少服务商、内存或价格时
+// 无法可靠判断，返回空字符串。
+func dealFingerprint(deal *models.Deal, locations []string) string {
+	provider := strings.ToLower(strings.Join(strings.Fields(deal.Provider), ""))
+	if provider == "" || deal.RAMMB == 0 || deal.MonthlyPrice <= 0 {
+		return ""
+	}
+
+	location := ""
+	if len(locations) > 0 {
+		location = strings.ToLower(strings.Join(strings.Fields(locations[0]), ""))
+	}
+
+	traffic := strconv.Itoa(deal.TrafficGB)
+	if deal.Unlimited {
+		traffic = "unlimited"
+	}
+
+	key := strings.Join([]string{
+		provider,
+		strconv.Itoa(deal.CPUCores),
+		strconv.Itoa(deal.RAMMB),
+		strconv.Itoa(deal.DiskGB),
+		traffic,
+		strconv.FormatFloat(deal.MonthlyPrice, 'f', 2, 64),
+		deal.Currency,
+		location,
+	}, "|")
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// RecomputeDealMetrics 重新计算所有优惠的数值字段，解析规则更新后用于回填旧数据
+//
+// 只写入数值字段有变化的优惠，且不更新updated_at，规则未变时启动时不产生写入。
+func RecomputeDealMetrics() (int, error) {
+	updated := 0
+	var deals []models.Deal
+	err := config.DB.Unscoped().FindInBatches(&deals, 500, func(tx *gorm.DB, batch int) error {
+		for i := range deals {
+			before := dealMetricColumns(&deals[i])
+			computeDealMetrics(&deals[i])
+			after := dealMetricColumns(&deals[i])
+			if reflect.DeepEqual(before, after) {
+				continue
+			}
+			if err := tx.Model(&deals[i]).UpdateColumns(after).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	}).Error
+	return updated, err
+}
+
+// dealMetricColumns 优惠的计算字段，用于判断是否需要回填
+func dealMetricColumns(deal *models.Deal) map[string]interface{} {
+	return map[string]interface{}{
+		"cpu_cores":          deal.CPUCores,
+		"ram_mb":             deal.RAMMB,
+		"disk_gb":            deal.DiskGB,
+		"disk_type":          deal.DiskType,
+		"traffic_gb":         deal.TrafficGB,
+		"unlimited":          deal.Unlimited,
+		"port_mbps":          deal.PortMbps,
+		"ipv4_count":         deal.IPv4Count,
+		"ipv6":               deal.IPv6,
+		"monthly_price":      deal.MonthlyPrice,
+		"price_per_gb_ram":   deal.PricePerGBRAM,
+		"fingerprint":        deal.Fingerprint,
+		"base_monthly_price": deal.BaseMonthlyPrice,
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"fmt"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// DealLink 主优惠在各来源中的链接
+type DealLink struct {
+	DealID       uint    `json:"deal_id"`
+	SourceID     *uint   `json:"source_id"`
+	ExternalID   string  `json:"external_id"`
+	Title        string  `json:"title"`
+	URL          string  `json:"url"`
+	Price        float64 `json:"price"`
+	Currency     string  `json:"currency"`
+	BillingCycle string  `json:"billing_cycle"`
+}
+
+// ListDealLinks 获取主优惠及合并到它的所有优惠的来源链接
+func ListDealLinks(id uint) ([]DealLink, error) {
+	var deals []models.Deal
+	err := config.DB.Where("id = ? OR canonical_id = ?", id, id).Order("id").Find(&deals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]DealLink, 0, len(deals))
+	for _, d := range deals {
+		links = append(links, DealLink{
+			DealID:       d.ID,
+			SourceID:     d.SourceID,
+			ExternalID:   d.ExternalID,
+			Title:        d.Title,
+			URL:          d.URL,
+			Price:        d.Price,
+			Currency:     d.Currency,
+			BillingCycle: d.BillingCycle,
+		})
+	}
+	return links, nil
+}
+
+// MergeDeals 将多个优惠合并到主优惠，已合并到这些优惠的记录一并转移
+func MergeDeals(canonicalID uint, dealIDs []uint, userID uint, reason string) error {
+	dealIDs = uniqueIDs(dealIDs, canonicalID)
+	if len(dealIDs) == 0 {
+		return fmt.Errorf("%w: 没有需要合并的优惠", ErrInvalidInput)
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var canonical models.Deal
+		if err := tx.First(&canonical, canonicalID).Error; err != nil {
+			return err
+		}
+		if canonical.CanonicalID != nil {
+			return fmt.Errorf("%w: 优惠 %d 已合并到 %d，请合并到主优惠", ErrInvalidInput, canonical.ID, *canonical.CanonicalID)
+		}
+
+		var count int64
+		if err := tx.Model(&models.Deal{}).Where("id IN ?", dealIDs).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(dealIDs) {
+			return fmt.Errorf("%w: 部分优惠不存在", ErrInvalidInput)
+		}
+
+		err := tx.Model(&models.Deal{}).
+			Where("id IN ? OR canonical_id IN ?", dealIDs, dealIDs).
+			Update("canonical_id", canonicalID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.DealMergeLog{
+			Action:      models.MergeActionMerge,
+			CanonicalID: canonicalID,
+			DealIDs:     joinIDs(dealIDs),
+			UserID:      &userID,
+			Reason:      strings.TrimSpace(reason),
+		}).Error
+	})
+}
+
+// SplitDeals 将合并到主优惠的优惠拆分出来，各自重新成为独立的优惠
+func SplitDeals(canonicalID uint, dealIDs []uint, userID uint, reason string) error {
+	dealIDs = uniqueIDs(dealIDs, canonicalID)
+	if len(dealIDs) == 0 {
+		return fmt.Errorf("%w: 没有需要拆分的优惠", ErrInvalidInput)
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.Deal{}, canonicalID).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Deal{}).
+			Where("id IN ? AND canonical_id = ?", dealIDs, canonicalID).
+			Update("canonical_id", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if int(result.RowsAffected) != len(dealIDs) {
+			return fmt.Errorf("%w: 部分优惠未合并到优惠 %d", ErrInvalidInput, canonicalID)
+		}
+
+		return tx.Create(&models.DealMergeLog{
+			Action:      models.MergeActionSplit,
+			CanonicalID: canonicalID,
+			DealIDs:     joinIDs(dealIDs),
+			UserID:      &userID,
+			Reason:      strings.TrimSpace(reason),
+		}).Error
+	})
+}
+
+// promoteDuplicate 主优惠删除后，最早合并到它的优惠成为新的主优惠，其余重复记录转移到新的主优惠
+func promoteDuplicate(tx *gorm.DB, canonicalID uint) error {
+	var ids []uint
+	err := tx.Model(&models.Deal{}).Where("canonical_id = ?", canonicalID).Order("id").Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	promoted, rest := ids[0], ids[1:]
+	if err := tx.Model(&models.Deal{}).Where("id = ?", promoted).Update("canonical_id", nil).Error; err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		if err := tx.Model(&models.Deal{}).Where("id IN ?", rest).Update("canonical_id", promoted).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Create(&models.DealMergeLog{
+		Action:      models.MergeActionPromote,
+		CanonicalID: promoted,
+		DealIDs:     joinIDs(rest),
+		Reason:      fmt.Sprintf("原主优惠 %d 已删除", canonicalID),
+	}).Error
+}
+
+// ListDealMergeLogs 获取与主优惠相关的合并记录，按时间倒序
+func ListDealMergeLogs(canonicalID uint) ([]models.DealMergeLog, error) {
+	var logs []models.DealMergeLog
+	err := config.DB.Where("canonical_id = ?", canonicalID).Order("id DESC").Find(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// findDuplicateDeal 查找其他来源中指纹相同的主优惠
+func findDuplicateDeal(deal *models.Deal) (*models.Deal, error) {
+	if deal.Fingerprint == "" {
+		return nil, nil
+	}
+
+	db := config.DB.Where("fingerprint = ? AND canonical_id IS NULL", deal.Fingerprint)
+	if deal.ID != 0 {
+		db = db.Where("id <> ?", deal.ID)
+	}
+	if deal.SourceID != nil {
+		db = db.Where("source_id IS NULL OR source_id <> ?", *deal.SourceID)
+	}
+
+	var deals []models.Deal
+	if err := db.Order("id").Limit(1).Find(&deals).Error; err != nil {
+		return nil, err
+	}
+	if len(deals) == 0 {
+		return nil, nil
+	}
+	return &deals[0], nil
+}
+
+// mergeDuplicate 记录按指纹自动合并，已合并到该优惠的记录一并转移到新的主优惠
+func mergeDuplicate(tx *gorm.DB, deal *models.Deal) error {
+	err := tx.Model(&models.Deal{}).Where("canonical_id = ?", deal.ID).Update("canonical_id", *deal.CanonicalID).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.DealMergeLog{
+		Action:      models.MergeActionAuto,
+		CanonicalID: *deal.CanonicalID,
+		DealIDs:     joinIDs([]uint{deal.ID}),
+		Fingerprint: deal.Fingerprint,
+		Reason:      "指纹相同",
+	}).Error
+}
+
+// uniqueIDs 去重并排除主优惠自身
+func uniqueIDs(ids []uint, exclude uint) []uint {
+	seen := make(map[uint]bool)
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || id == exclude || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
+
+// joinIDs 将ID列表格式化为逗号分隔的字符串
+func joinIDs(ids []uint) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, ",")
+}
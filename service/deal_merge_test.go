@@ -0,0 +1,139 @@
+package service
+
+import (
+	"testing"
+
+	"go-nextjs/config"
+	"go-nextjs/models"
+)
+
+// createMergedDeals 创建一个主优惠和n个合并到它的重复记录
+func createMergedDeals(t *testing.T, n int) (uint, []uint) {
+	t.Helper()
+	canonical := &models.Deal{Title: "KVM", Price: 5, Status: models.DealStatusActive}
+	if err := config.DB.Create(canonical).Error; err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	for i := 0; i < n; i++ {
+		deal := &models.Deal{Title: "KVM", Price: 5, Status: models.DealStatusActive, CanonicalID: &canonical.ID}
+		if err := config.DB.Create(deal).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, deal.ID)
+	}
+	return canonical.ID, ids
+}
+
+func TestDeleteDealPromotesOldestDuplicate(t *testing.T) {
+	setupTestDB(t)
+	canonicalID, ids := createMergedDeals(t, 3)
+
+	if err := DeleteDeal(canonicalID); err != nil {
+		t.Fatal(err)
+	}
+
+	promoted, err := GetDeal(ids[0])
+	if err != nil {
+		t.Fatalf("duplicate %d deleted: %v", ids[0], err)
+	}
+	if promoted.CanonicalID != nil {
+		t.Errorf("promoted canonical_id = %d, want nil", *promoted.CanonicalID)
+	}
+	for _, id := range ids[1:] {
+		deal, err := GetDeal(id)
+		if err != nil {
+			t.Fatalf("duplicate %d deleted: %v", id, err)
+		}
+		if deal.CanonicalID == nil || *deal.CanonicalID != promoted.ID {
+			t.Errorf("deal %d canonical_id = %v, want %d", id, deal.CanonicalID, promoted.ID)
+		}
+	}
+
+	logs, err := ListDealMergeLogs(promoted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || logs[0].Action != models.MergeActionPromote || logs[0].DealIDs != joinIDs(ids[1:]) {
+		t.Errorf("merge logs = %+v", logs)
+	}
+}
+
+func TestDeleteDealSingleDuplicate(t *testing.T) {
+	setupTestDB(t)
+	canonicalID, ids := createMergedDeals(t, 1)
+
+	if err := DeleteDeal(canonicalID); err != nil {
+		t.Fatal(err)
+	}
+	deal, err := GetDeal(ids[0])
+	if err != nil || deal.CanonicalID != nil {
+		t.Fatalf("GetDeal(%d) = %+v, %v, want independent deal", ids[0], deal, err)
+	}
+	if _, err := GetDeal(canonicalID); err == nil {
+		t.Error("canonical deal still exists")
+	}
+}
+
+// saveSourceDeal 以来源条目的方式保存优惠
+func saveSourceDeal(t *testing.T, sourceID uint, price float64) *models.Deal {
+	t.Helper()
+	deal := &models.Deal{SourceID: &sourceID}
+	input := &DealInput{Title: "KVM", Provider: "RackNerd", Price: price}
+	input.RAM = "1GB"
+	if err := applyDealInput(deal, input); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveDeal(deal, nil); err != nil {
+		t.Fatal(err)
+	}
+	return deal
+}
+
+func TestSaveDealMergesWhenFingerprintChanges(t *testing.T) {
+	setupTestDB(t)
+	canonical := saveSourceDeal(t, 1, 10)
+	deal := saveSourceDeal(t, 2, 12)
+	if deal.CanonicalID != nil {
+		t.Fatalf("deal merged to %d before fingerprints match", *deal.CanonicalID)
+	}
+
+	// 价格更新后与其他来源的优惠相同
+	old := *deal
+	deal.Price = 10
+	computeDealMetrics(deal)
+	if err := saveDeal(deal, &old); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := GetDeal(deal.ID)
+	if stored.CanonicalID == nil || *stored.CanonicalID != canonical.ID {
+		t.Fatalf("canonical_id = %v, want %d", stored.CanonicalID, canonical.ID)
+	}
+	logs, _ := ListDealMergeLogs(canonical.ID)
+	if len(logs) != 1 || logs[0].Action != models.MergeActionAuto || logs[0].DealIDs != joinIDs([]uint{deal.ID}) {
+		t.Errorf("merge logs = %+v", logs)
+	}
+}
+
+func TestSaveDealKeepsSplitDeal(t *testing.T) {
+	setupTestDB(t)
+	canonical := saveSourceDeal(t, 1, 10)
+	deal := saveSourceDeal(t, 2, 10)
+	if deal.CanonicalID == nil || *deal.CanonicalID != canonical.ID {
+		t.Fatalf("canonical_id = %v, want %d", deal.CanonicalID, canonical.ID)
+	}
+	if err := SplitDeals(canonical.ID, []uint{deal.ID}, 1, "不是同一优惠"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 指纹未变化时不重新合并管理员拆分的优惠
+	deal, _ = GetDeal(deal.ID)
+	old := *deal
+	deal.Title = "KVM 新标题"
+	if err := saveDeal(deal, &old); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := GetDeal(deal.ID); stored.CanonicalID != nil {
+		t.Errorf("split deal merged again to %d", *stored.CanonicalID)
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 事件查询的分页大小
+const (
+	defaultEventPageSize = 50
+	maxEventPageSize     = 200
+)
+
+// DealEventHandler 优惠事件的订阅回调
+type DealEventHandler func(event models.DealEvent)
+
+var (
+	subscribersMu sync.RWMutex
+	subscribers   []DealEventHandler
+)
+
+// SubscribeDealEvents 订阅优惠事件，事件写入数据库后异步回调
+func SubscribeDealEvents(handler DealEventHandler) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	subscribers = append(subscribers, handler)
+}
+
+// publishDealEvent 将事件分发给所有订阅者，单个订阅者出错不影响其他订阅者
+func publishDealEvent(event models.DealEvent) {
+	subscribersMu.RLock()
+	handlers := append([]DealEventHandler(nil), subscribers...)
+	subscribersMu.RUnlock()
+
+	for _, handler := range handlers {
+		go func(handler DealEventHandler) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("处理优惠事件 %d 失败: %v", event.ID, r)
+				}
+			}()
+			handler(event)
+		}(handler)
+	}
+}
+
+// saveDeal 保存优惠并记录价格变化，old为保存前的状态，新建时为nil
+//
+// 未合并的优惠指纹变化时（包括新建），其他来源已有同一优惠则自动合并到该优惠。
+func saveDeal(deal *models.Deal, old *models.Deal) error {
+	var duplicate *models.Deal
+	if deal.CanonicalID == nil && (old == nil || old.Fingerprint != deal.Fingerprint) {
+		var err error
+		if duplicate, err = findDuplicateDeal(deal); err != nil {
+			return err
+		}
+	}
+
+	var event *models.DealEvent
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if duplicate != nil {
+			deal.CanonicalID = &duplicate.ID
+		}
+		if old == nil {
+			if err := tx.Create(deal).Error; err != nil {
+				return err
+			}
+			if duplicate != nil {
+				if err := mergeDuplicate(tx, deal); err != nil {
+					return err
+				}
+			}
+			return tx.Create(newPriceHistory(deal, deal.CreatedAt)).Error
+		}
+
+		if err := tx.Save(deal).Error; err != nil {
+			return err
+		}
+		if duplicate != nil {
+			if err := mergeDuplicate(tx, deal); err != nil {
+				return err
+			}
+		}
+		if !priceChanged(old, deal) {
+			return nil
+		}
+
+		// 早于价格历史功能创建的优惠没有初始记录，先补上变化前的价格
+		var count int64
+		if err := tx.Model(&models.DealPriceHistory{}).Where("deal_id = ?", deal.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := tx.Create(newPriceHistory(old, old.UpdatedAt)).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(newPriceHistory(deal, time.Now())).Error; err != nil {
+			return err
+		}
+
+		event = detectPriceDrop(old, deal)
+		if event != nil {
+			return tx.Create(event).Error
+		}
+		return nil
+	})
+	if err != nil {
+		if duplicate != nil {
+			deal.CanonicalID = nil
+		}
+		return err
+	}
+
+	if event != nil {
+		log.Printf("优惠 %d 降价 %.1f%%: %.2f -> %.2f %s", deal.ID, event.DropPercent, event.OldPrice, event.NewPrice, event.Currency)
+		publishDealEvent(*event)
+	}
+	return nil
+}
+
+// priceChanged 判断价格、货币或计费周期是否变化
+func priceChanged(old *models.Deal, deal *models.Deal) bool {
+	return old.Price != deal.Price || old.Currency != deal.Currency || old.BillingCycle != deal.BillingCycle
+}
+
+// detectPriceDrop 月付价格下降超过阈值时生成降价事件，货币变化时无法比较
+func detectPriceDrop(old *models.Deal, deal *models.Deal) *models.DealEvent {
+	if old.Currency != deal.Currency || old.MonthlyPrice <= 0 || deal.MonthlyPrice >= old.MonthlyPrice {
+		return nil
+	}
+
+	percent := (old.MonthlyPrice - deal.MonthlyPrice) / old.MonthlyPrice * 100
+	if percent < config.PriceDropPercent {
+		return nil
+	}
+	return &models.DealEvent{
+		DealID:      deal.ID,
+		Type:        models.DealEventPriceDrop,
+		OldPrice:    old.MonthlyPrice,
+		NewPrice:    deal.MonthlyPrice,
+		Currency:    deal.Currency,
+		DropPercent: percent,
+	}
+}
+
+// newPriceHistory 根据优惠当前的价格创建价格记录
+func newPriceHistory(deal *models.Deal, observedAt time.Time) *models.DealPriceHistory {
+	if observedAt.IsZero() {
+		observedAt = time.Now()
+	}
+	return &models.DealPriceHistory{
+		DealID:       deal.ID,
+		Price:        deal.Price,
+		Currency:     deal.Currency,
+		BillingCycle: deal.BillingCycle,
+		MonthlyPrice: deal.MonthlyPrice,
+		ObservedAt:   observedAt,
+	}
+}
+
+// ListDealPrices 获取优惠的价格历史，按时间升序
+func ListDealPrices(dealID uint) ([]models.DealPriceHistory, error) {
+	if _, err := GetDeal(dealID); err != nil {
+		return nil, err
+	}
+
+	var prices []models.DealPriceHistory
+	err := config.DB.Where("deal_id = ?", dealID).Order("observed_at, id").Find(&prices).Error
+	if err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+// ListDealEvents 获取ID大于afterID的事件，按ID升序，消费方记录最后一个ID继续拉取
+func ListDealEvents(eventType string, afterID uint, limit int) ([]models.DealEvent, error) {
+	if limit <= 0 {
+		limit = defaultEventPageSize
+	}
+	if limit > maxEventPageSize {
+		limit = maxEventPageSize
+	}
+
+	db := config.DB.Where("id > ?", afterID)
+	if eventType != "" {
+		db = db.Where("type = ?", eventType)
+	}
+
+	var events []models.DealEvent
+	if err := db.Order("id").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
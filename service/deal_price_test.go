@@ -0,0 +1,71 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"go-nextjs/config"
+	"go-nextjs/models"
+)
+
+// updateDealPriceTo 以新的价格更新优惠
+func updateDealPriceTo(t *testing.T, id uint, price float64) {
+	t.Helper()
+	if _, err := UpdateDeal(id, &DealInput{Title: "KVM", Price: price, BillingCycle: models.BillingMonthly}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateDealCreatesPriceDropEvent(t *testing.T) {
+	setupTestDB(t)
+	config.PriceDropPercent = 10
+
+	received := make(chan models.DealEvent, 1)
+	SubscribeDealEvents(func(event models.DealEvent) { received <- event })
+	t.Cleanup(func() {
+		subscribersMu.Lock()
+		subscribers = nil
+		subscribersMu.Unlock()
+	})
+
+	deal, err := CreateDeal(&DealInput{Title: "KVM", Price: 10, BillingCycle: models.BillingMonthly})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 降价幅度低于阈值时只记录价格
+	updateDealPriceTo(t, deal.ID, 9.5)
+	if events, _ := ListDealEvents("", 0, 0); len(events) != 0 {
+		t.Fatalf("events = %+v, want none", events)
+	}
+
+	updateDealPriceTo(t, deal.ID, 7.6)
+	events, err := ListDealEvents(models.DealEventPriceDrop, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("events = %d, want 1", len(events))
+	}
+	event := events[0]
+	if event.DealID != deal.ID || event.OldPrice != 9.5 || event.NewPrice != 7.6 || event.DropPercent < 19.9 || event.DropPercent > 20.1 {
+		t.Errorf("event = %+v", event)
+	}
+
+	select {
+	case got := <-received:
+		if got.ID != event.ID {
+			t.Errorf("published event = %d, want %d", got.ID, event.ID)
+		}
+	case <-time.After(time.Second):
+		t.Error("price drop event not published")
+	}
+
+	prices, err := ListDealPrices(deal.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 3 || prices[0].Price != 10 || prices[2].Price != 7.6 {
+		t.Errorf("price history = %+v", prices)
+	}
+}
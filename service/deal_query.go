@@ -0,0 +1,177 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"strings"
+)
+
+// 优惠列表排序方式
+const (
+	DealSortRecent        = "recent"           // 最新发布
+	DealSortPrice         = "price"            // 基准货币月付价格从低到高
+	DealSortPricePerGBRAM = "price_per_gb_ram" // 每GB内存价格从低到高
+)
+
+// 分页大小
+const (
+	defaultDealPageSize = 20
+	maxDealPageSize     = 100
+)
+
+// DealQuery 优惠列表的筛选、排序和分页参数
+type DealQuery struct {
+	Location string  // 位置关键字
+	Provider string  // 服务商
+	MinCPU   int     // 最少CPU核心数
+	MinRAMMB int     // 最小内存（MB）
+	MinDisk  int     // 最小硬盘（GB）
+	MaxPrice float64 // 最高基准货币月付价格，0表示不限
+	IPv6     bool    // 只看提供IPv6的优惠
+	// IncludeInactive 包含售罄、失效和已归档的优惠
+	IncludeInactive bool
+	Sort            string // 排序方式
+	Cursor          string // 上一页返回的游标
+	Limit           int    // 每页数量
+}
+
+// DealPage 一页优惠列表
+type DealPage struct {
+	Deals        []models.Deal `json:"deals"`
+	NextCursor   string        `json:"next_cursor"`   // 为空表示没有下一页
+	BaseCurrency string        `json:"base_currency"` // base_monthly_price 和 price_per_gb_ram 的货币
+}
+
+// dealCursor 游标内容，记录上一页最后一条的排序值和ID
+type dealCursor struct {
+	Sort  string  `json:"s"`
+	Value float64 `json:"v"`
+	ID    uint    `json:"id"`
+}
+
+// ListDeals 按条件分页查询优惠列表
+func ListDeals(q *DealQuery) (*DealPage, error) {
+	if q.Sort == "" {
+		q.Sort = DealSortRecent
+	}
+	if q.Sort != DealSortRecent && q.Sort != DealSortPrice && q.Sort != DealSortPricePerGBRAM {
+		return nil, fmt.Errorf("%w: 无效的排序方式 %s", ErrInvalidInput, q.Sort)
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultDealPageSize
+	}
+	if q.Limit > maxDealPageSize {
+		q.Limit = maxDealPageSize
+	}
+
+	// 合并到其他优惠的重复记录不单独展示
+	db := config.DB.Model(&models.Deal{}).Where("canonical_id IS NULL")
+	if !q.IncludeInactive {
+		db = db.Where("status = ?", models.DealStatusActive)
+	}
+	if location := strings.TrimSpace(q.Location); location != "" {
+		db = db.Where(`LOWER(location) LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(location))+"%")
+	}
+	if provider := strings.TrimSpace(q.Provider); provider != "" {
+		db = db.Where("LOWER(provider) = ?", strings.ToLower(provider))
+	}
+	if q.MinCPU > 0 {
+		db = db.Where("cpu_cores >= ?", q.MinCPU)
+	}
+	if q.MinRAMMB > 0 {
+		db = db.Where("ram_mb >= ?", q.MinRAMMB)
+	}
+	if q.MinDisk > 0 {
+		db = db.Where("disk_gb >= ?", q.MinDisk)
+	}
+	if q.MaxPrice > 0 {
+		db = db.Where("base_monthly_price <= ?", q.MaxPrice)
+	}
+	if q.IPv6 {
+		db = db.Where("ipv6 = ?", true)
+	}
+
+	var cursor *dealCursor
+	if q.Cursor != "" {
+		c, err := decodeDealCursor(q.Cursor)
+		if err != nil || c.Sort != q.Sort {
+			return nil, fmt.Errorf("%w: 无效的游标", ErrInvalidInput)
+		}
+		cursor = c
+	}
+
+	// 基于排序值和ID做键集分页，翻页过程中新增数据不会导致重复或遗漏
+	switch q.Sort {
+	case DealSortPrice:
+		// 缺少汇率的优惠无法与其他货币比较，不参与价格排序
+		db = db.Where("base_monthly_price IS NOT NULL")
+		if cursor != nil {
+			db = db.Where("base_monthly_price > ? OR (base_monthly_price = ? AND id > ?)", cursor.Value, cursor.Value, cursor.ID)
+		}
+		db = db.Order("base_monthly_price ASC, id ASC")
+	case DealSortPricePerGBRAM:
+		// 内存或汇率未知的优惠无法比较，不参与该排序
+		db = db.Where("ram_mb > 0 AND base_monthly_price IS NOT NULL")
+		if cursor != nil {
+			db = db.Where("price_per_gb_ram > ? OR (price_per_gb_ram = ? AND id > ?)", cursor.Value, cursor.Value, cursor.ID)
+		}
+		db = db.Order("price_per_gb_ram ASC, id ASC")
+	default:
+		// ID自增，与创建时间顺序一致
+		if cursor != nil {
+			db = db.Where("id < ?", cursor.ID)
+		}
+		db = db.Order("id DESC")
+	}
+
+	// 多查一条用于判断是否还有下一页
+	var deals []models.Deal
+	if err := db.Limit(q.Limit + 1).Find(&deals).Error; err != nil {
+		return nil, err
+	}
+
+	page := &DealPage{Deals: deals, BaseCurrency: config.BaseCurrency}
+	if len(deals) > q.Limit {
+		page.Deals = deals[:q.Limit]
+		last := page.Deals[q.Limit-1]
+		next := dealCursor{Sort: q.Sort, ID: last.ID}
+		switch q.Sort {
+		case DealSortPrice:
+			next.Value = *last.BaseMonthlyPrice
+		case DealSortPricePerGBRAM:
+			next.Value = last.PricePerGBRAM
+		}
+		page.NextCursor = encodeDealCursor(&next)
+	}
+	return page, nil
+}
+
+// likeEscaper 转义LIKE中的通配符，配合 ESCAPE '\' 使用
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike 使输入在LIKE中按字面匹配
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// encodeDealCursor 将游标编码为不透明字符串
+func encodeDealCursor(c *dealCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeDealCursor 解码游标
+func decodeDealCursor(s string) (*dealCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c dealCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"sort"
+	"testing"
+
+	"go-nextjs/config"
+	"go-nextjs/models"
+)
+
+func TestListDealsLocationIsLiteral(t *testing.T) {
+	setupTestDB(t)
+	for _, location := range []string{"US_West", "USAWest", "100% Uptime DC", "1000 Uptime DC", `C:\DC`, "Los Angeles"} {
+		deal := &models.Deal{Title: location, Price: 5, Location: location, Status: models.DealStatusActive}
+		if err := config.DB.Create(deal).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		location string
+		want     []string
+	}{
+		{"us_w", []string{"US_West"}},
+		{"_", []string{"US_West"}},
+		{"0%", []string{"100% Uptime DC"}},
+		{"%", []string{"100% Uptime DC"}},
+		{`\`, []string{`C:\DC`}},
+		{"angeles", []string{"Los Angeles"}},
+		{"uptime", []string{"100% Uptime DC", "1000 Uptime DC"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			page, err := ListDeals(&DealQuery{Location: tt.location})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range page.Deals {
+				got = append(got, d.Location)
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("locations = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("locations = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"go-nextjs/pkg/feed"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// checkBatchSize 每轮最多检查的优惠数量
+	checkBatchSize = 100
+	// checkConcurrency 同时检查的优惠数量
+	checkConcurrency = 8
+	// checkTimeout 单个购买链接的检查超时
+	checkTimeout = 20 * time.Second
+)
+
+// checkAvailability 检查购买页面，测试中可替换
+var checkAvailability = feed.CheckAvailability
+
+// ArchiveEndedDeals 归档已过结束时间的优惠
+func ArchiveEndedDeals() (int64, error) {
+	result := config.DB.Model(&models.Deal{}).
+		Where("ends_at IS NOT NULL AND ends_at < ? AND status <> ?", time.Now(), models.DealStatusArchived).
+		Update("status", models.DealStatusArchived)
+	return result.RowsAffected, result.Error
+}
+
+// CheckDealAvailability 重新检查有效和售罄优惠的购买链接，返回检查数量和状态变化数量
+//
+// 页面返回404或410时标记为已失效，所有套餐都缺货时标记为售罄，
+// 售罄的优惠页面恢复正常后重新标记为有效。请求失败或其他状态码视为暂时性问题，不改变状态。
+func CheckDealAvailability(ctx context.Context) (int, int, error) {
+	var deals []models.Deal
+	err := config.DB.
+		Where("status IN ? AND url <> ''", []string{models.DealStatusActive, models.DealStatusSoldOut}).
+		Where("checked_at IS NULL OR checked_at < ?", time.Now().Add(-config.DealCheckInterval)).
+		Order("checked_at IS NOT NULL, checked_at, id").
+		Limit(checkBatchSize).
+		Find(&deals).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var mu sync.Mutex
+	changed := 0
+	sem := make(chan struct{}, checkConcurrency)
+	var wg sync.WaitGroup
+	for i := range deals {
+		if ctx.Err() != nil {
+			break
+		}
+		deal := &deals[i]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			oldStatus := deal.Status
+			status, err := checkDeal(ctx, deal)
+			if err != nil {
+				log.Printf("检查优惠 %d 失败: %v", deal.ID, err)
+				return
+			}
+			if status != oldStatus {
+				log.Printf("优惠 %d 状态变化: %s -> %s", deal.ID, oldStatus, status)
+				mu.Lock()
+				changed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return len(deals), changed, nil
+}
+
+// checkDeal 检查单个优惠并保存检查时间和新状态
+func checkDeal(ctx context.Context, deal *models.Deal) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	oldStatus, status := deal.Status, deal.Status
+	result, err := checkAvailability(ctx, deal.URL, config.DealStockMarkers, config.DealOrderMarkers)
+	if err == nil {
+		switch {
+		case result.StatusCode == http.StatusNotFound || result.StatusCode == http.StatusGone:
+			status = models.DealStatusExpired
+		case result.StatusCode == http.StatusOK && result.SoldOut:
+			status = models.DealStatusSoldOut
+		case result.StatusCode == http.StatusOK:
+			status = models.DealStatusActive
+		}
+	}
+
+	// 请求失败也记录检查时间，避免每轮都重复请求同一个不可用的链接。
+	// 检查结果不是对优惠的修改，不更新updated_at，避免打乱按更新时间的排序
+	if updateErr := config.DB.Model(deal).UpdateColumn("checked_at", time.Now()).Error; updateErr != nil && err == nil {
+		err = updateErr
+	}
+	if err != nil || status == oldStatus {
+		return oldStatus, err
+	}
+
+	// 只在状态未被修改时更新，检查期间管理员修改的状态优先
+	updated := config.DB.Model(&models.Deal{}).
+		Where("id = ? AND status = ?", deal.ID, oldStatus).
+		UpdateColumn("status", status)
+	if updated.Error != nil {
+		return oldStatus, updated.Error
+	}
+	if updated.RowsAffected == 0 {
+		return oldStatus, nil
+	}
+	deal.Status = status
+	return status, nil
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"go-nextjs/pkg/feed"
+)
+
+// stubAvailability 替换购买页面检查，检查期间执行during
+func stubAvailability(t *testing.T, result *feed.Availability, during func()) {
+	t.Helper()
+	original := checkAvailability
+	checkAvailability = func(ctx context.Context, url string, markers, orderMarkers []string) (*feed.Availability, error) {
+		if during != nil {
+			during()
+		}
+		return result, nil
+	}
+	t.Cleanup(func() { checkAvailability = original })
+}
+
+func createCheckedDeal(t *testing.T) *models.Deal {
+	t.Helper()
+	deal := &models.Deal{Title: "KVM", Price: 5, URL: "https://vendor.test/cart", Status: models.DealStatusActive}
+	if err := config.DB.Create(deal).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 更新时间设为过去，便于确认检查不会修改它
+	past := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	config.DB.Model(deal).UpdateColumn("updated_at", past)
+	deal.UpdatedAt = past
+	return deal
+}
+
+func TestCheckDealUpdatesStatusWithoutTouchingUpdatedAt(t *testing.T) {
+	setupTestDB(t)
+	deal := createCheckedDeal(t)
+	stubAvailability(t, &feed.Availability{StatusCode: http.StatusNotFound}, nil)
+
+	status, err := checkDeal(context.Background(), deal)
+	if err != nil || status != models.DealStatusExpired {
+		t.Fatalf("checkDeal() = %s, %v, want expired", status, err)
+	}
+	stored, _ := GetDeal(deal.ID)
+	if stored.Status != models.DealStatusExpired || stored.CheckedAt == nil {
+		t.Errorf("stored deal = status %s checked_at %v", stored.Status, stored.CheckedAt)
+	}
+	if !stored.UpdatedAt.Equal(deal.UpdatedAt) {
+		t.Errorf("updated_at = %v, want unchanged %v", stored.UpdatedAt, deal.UpdatedAt)
+	}
+}
+
+func TestCheckDealKeepsConcurrentStatusChange(t *testing.T) {
+	setupTestDB(t)
+	deal := createCheckedDeal(t)
+	// 检查期间管理员归档了该优惠
+	stubAvailability(t, &feed.Availability{StatusCode: http.StatusOK, SoldOut: true}, func() {
+		config.DB.Model(&models.Deal{}).Where("id = ?", deal.ID).Update("status", models.DealStatusArchived)
+	})
+
+	status, err := checkDeal(context.Background(), deal)
+	if err != nil || status != models.DealStatusActive {
+		t.Fatalf("checkDeal() = %s, %v, want unchanged", status, err)
+	}
+	if stored, _ := GetDeal(deal.ID); stored.Status != models.DealStatusArchived || stored.CheckedAt == nil {
+		t.Errorf("stored deal = status %s checked_at %v, want archived and checked", stored.Status, stored.CheckedAt)
+	}
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"go-nextjs/pkg/currency"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// 汇率文件格式
+const (
+	RateFormatCSV = "csv"
+	RateFormatECB = "ecb"
+)
+
+var (
+	ratesMu sync.RWMutex
+	// rates 当前基准货币的汇率缓存，为nil时在下次使用时从数据库加载
+	rates map[string]float64
+)
+
+// RateImport 汇率导入结果
+type RateImport struct {
+	Imported int    `json:"imported"` // 导入的汇率数量
+	Date     string `json:"date"`     // 汇率日期，仅ECB文件提供
+	Deals    int    `json:"deals"`    // 重新折算价格的优惠数量
+}
+
+// ListExchangeRates 获取当前基准货币的所有汇率
+func ListExchangeRates() ([]models.ExchangeRate, error) {
+	var list []models.ExchangeRate
+	err := config.DB.Where("base = ?", config.BaseCurrency).Order("currency").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// SetExchangeRate 设置货币的汇率，并重新折算该货币优惠的价格
+func SetExchangeRate(code string, rate float64) (*models.ExchangeRate, int, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !currency.ValidCode(code) {
+		return nil, 0, fmt.Errorf("%w: 无效的货币代码 %s", ErrInvalidInput, code)
+	}
+	if code == config.BaseCurrency {
+		return nil, 0, fmt.Errorf("%w: 不能设置基准货币 %s 的汇率", ErrInvalidInput, code)
+	}
+	if rate <= 0 {
+		return nil, 0, fmt.Errorf("%w: 汇率必须大于0", ErrInvalidInput)
+	}
+
+	var saved models.ExchangeRate
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		saved, err = saveExchangeRate(tx, code, rate, models.RateSourceManual)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	n, err := refreshBasePrices([]string{code})
+	return &saved, n, err
+}
+
+// DeleteExchangeRate 删除货币的汇率，该货币的优惠将无法按价格排序
+func DeleteExchangeRate(code string) (int, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	result := config.DB.Where("base = ? AND currency = ?", config.BaseCurrency, code).Delete(&models.ExchangeRate{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return refreshBasePrices([]string{code})
+}
+
+// ImportExchangeRates 从CSV或欧洲央行XML文件导入汇率，format为空时根据内容判断
+//
+// CSV中的汇率需以当前基准货币为基准；ECB文件以欧元为基准，导入时换算为当前基准货币。
+func ImportExchangeRates(r io.Reader, format string) (*RateImport, error) {
+	reader := bufio.NewReader(r)
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = detectRateFormat(reader)
+	}
+
+	var (
+		parsed map[string]float64
+		date   string
+		source string
+		err    error
+	)
+	switch format {
+	case RateFormatCSV:
+		source = models.RateSourceCSV
+		parsed, err = currency.ParseCSV(reader)
+	case RateFormatECB:
+		source = models.RateSourceECB
+		parsed, date, err = currency.ParseECB(reader)
+		if err == nil {
+			parsed, err = currency.Rebase(parsed, config.BaseCurrency)
+		}
+	default:
+		return nil, fmt.Errorf("%w: 不支持的汇率格式 %s，可选 csv、ecb", ErrInvalidInput, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	delete(parsed, config.BaseCurrency)
+
+	codes := make([]string, 0, len(parsed))
+	for code := range parsed {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, code := range codes {
+			if _, err := saveExchangeRate(tx, code, parsed[code], source); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := refreshBasePrices(codes)
+	if err != nil {
+		return nil, err
+	}
+	return &RateImport{Imported: len(codes), Date: date, Deals: n}, nil
+}
+
+// detectRateFormat 根据第一个非空白字符判断文件格式
+func detectRateFormat(reader *bufio.Reader) string {
+	head, _ := reader.Peek(512)
+	if bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))), []byte("<")) {
+		return RateFormatECB
+	}
+	return RateFormatCSV
+}
+
+// saveExchangeRate 新建或更新当前基准货币下的汇率
+func saveExchangeRate(tx *gorm.DB, code string, rate float64, source string) (models.ExchangeRate, error) {
+	var list []models.ExchangeRate
+	if err := tx.Where("base = ? AND currency = ?", config.BaseCurrency, code).Limit(1).Find(&list).Error; err != nil {
+		return models.ExchangeRate{}, err
+	}
+
+	item := models.ExchangeRate{Base: config.BaseCurrency, Currency: code}
+	if len(list) > 0 {
+		item = list[0]
+	}
+	item.Rate = rate
+	item.Source = source
+	return item, tx.Save(&item).Error
+}
+
+// refreshBasePrices 汇率变化后清除缓存，并重新折算这些货币的优惠价格
+func refreshBasePrices(codes []string) (int, error) {
+	ratesMu.Lock()
+	rates = nil
+	ratesMu.Unlock()
+
+	updated := 0
+	var deals []models.Deal
+	err := config.DB.Unscoped().Where("currency IN ?", codes).FindInBatches(&deals, 200, func(tx *gorm.DB, batch int) error {
+		for i := range deals {
+			computeBasePrice(&deals[i])
+			err := tx.Model(&deals[i]).UpdateColumns(map[string]interface{}{
+				"base_monthly_price": deals[i].BaseMonthlyPrice,
+				"price_per_gb_ram":   deals[i].PricePerGBRAM,
+			}).Error
+			if err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	}).Error
+	return updated, err
+}
+
+// toBaseCurrency 将金额换算为基准货币，缺少汇率时返回false
+func toBaseCurrency(amount float64, code string) (float64, bool) {
+	if code == config.BaseCurrency {
+		return amount, true
+	}
+
+	ratesMu.RLock()
+	cached := rates
+	ratesMu.RUnlock()
+	if cached == nil {
+		var err error
+		if cached, err = loadRates(); err != nil {
+			return 0, false
+		}
+	}
+
+	rate, ok := cached[code]
+	if !ok || rate <= 0 {
+		return 0, false
+	}
+	return amount / rate, true
+}
+
+// loadRates 从数据库加载当前基准货币的汇率到缓存
+func loadRates() (map[string]float64, error) {
+	ratesMu.Lock()
+	defer ratesMu.Unlock()
+	if rates != nil {
+		return rates, nil
+	}
+
+	list, err := ListExchangeRates()
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[string]float64, len(list))
+	for _, item := range list {
+		loaded[item.Currency] = item.Rate
+	}
+	rates = loaded
+	return rates, nil
+}
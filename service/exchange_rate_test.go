@@ -0,0 +1,84 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"go-nextjs/config"
+	"go-nextjs/models"
+)
+
+func TestImportExchangeRatesCSV(t *testing.T) {
+	setupTestDB(t)
+	deal, err := CreateDeal(&DealInput{Title: "KVM", Price: 60, Currency: "EUR", BillingCycle: models.BillingAnnually})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deal.BaseMonthlyPrice != nil {
+		t.Fatalf("base price = %v before EUR rate is known", *deal.BaseMonthlyPrice)
+	}
+
+	result, err := ImportExchangeRates(strings.NewReader("currency,rate\nEUR,0.5\nCNY,7\nUSD,1\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 || result.Deals != 1 {
+		t.Errorf("import = %+v, want 2 rates and 1 deal", result)
+	}
+
+	// 年付60欧元即每月5欧元，按1美元兑0.5欧元折算为10美元
+	stored, _ := GetDeal(deal.ID)
+	if stored.BaseMonthlyPrice == nil || *stored.BaseMonthlyPrice != 10 {
+		t.Errorf("base price = %v, want 10", stored.BaseMonthlyPrice)
+	}
+	if price, ok := toBaseCurrency(70, "CNY"); !ok || price != 10 {
+		t.Errorf("toBaseCurrency(70 CNY) = %v, %v", price, ok)
+	}
+}
+
+func TestImportExchangeRatesECBRebase(t *testing.T) {
+	setupTestDB(t)
+	deal, err := CreateDeal(&DealInput{Title: "KVM", Price: 36, Currency: "JPY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ECB汇率以欧元为基准，导入时换算为基准货币美元
+	const ecb = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+<Cube><Cube time="2026-10-16"><Cube currency="USD" rate="1.2"/><Cube currency="JPY" rate="180"/></Cube></Cube>
+</gesmes:Envelope>`
+	result, err := ImportExchangeRates(strings.NewReader(ecb), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Date != "2026-10-16" || result.Imported != 2 || result.Deals != 1 {
+		t.Errorf("import = %+v", result)
+	}
+
+	list, err := ListExchangeRates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, item := range list {
+		if item.Base != config.BaseCurrency || item.Source != models.RateSourceECB {
+			t.Errorf("rate = %+v", item)
+		}
+		got[item.Currency] = item.Rate
+	}
+	if len(got) != 2 || !near(got["EUR"], 1/1.2) || !near(got["JPY"], 150) {
+		t.Errorf("rates = %v, want EUR 0.833 and JPY 150", got)
+	}
+
+	stored, _ := GetDeal(deal.ID)
+	if stored.BaseMonthlyPrice == nil || !near(*stored.BaseMonthlyPrice, 0.24) {
+		t.Errorf("base price = %v, want 0.24", stored.BaseMonthlyPrice)
+	}
+}
+
+// near 浮点数是否近似相等
+func near(a, b float64) bool {
+	d := a - b
+	return d < 1e-9 && d > -1e-9
+}
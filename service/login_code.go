@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"time"
+)
+
+// LoginCodeTTL 一次性登录码有效期，前端收到回调后立即换取令牌
+const LoginCodeTTL = time.Minute
+
+// ErrLoginCodeInvalid 登录码无效、已过期或已被使用
+var ErrLoginCodeInvalid = errors.New("登录码无效或已过期，请重新登录")
+
+// CreateLoginCode 为用户生成一次性登录码，只保存哈希
+func CreateLoginCode(userID uint) (string, error) {
+	raw, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	code := &models.LoginCode{
+		CodeHash:  hashToken(raw),
+		UserID:    userID,
+		ExpiresAt: now.Add(LoginCodeTTL),
+	}
+	if err := config.DB.Create(code).Error; err != nil {
+		return "", err
+	}
+
+	// 顺便清理早已过期的登录码
+	config.DB.Where("expires_at < ?", now.Add(-LoginCodeTTL)).Delete(&models.LoginCode{})
+
+	return raw, nil
+}
+
+// ConsumeLoginCode 校验并消费登录码，返回对应的用户，每个登录码只能使用一次
+func ConsumeLoginCode(raw string) (*models.User, error) {
+	if raw == "" {
+		return nil, ErrLoginCodeInvalid
+	}
+
+	var codes []models.LoginCode
+	if err := config.DB.Where("code_hash = ?", hashToken(raw)).Limit(1).Find(&codes).Error; err != nil {
+		return nil, err
+	}
+	if len(codes) == 0 {
+		return nil, ErrLoginCodeInvalid
+	}
+	code := codes[0]
+
+	now := time.Now()
+	if code.UsedAt != nil || now.After(code.ExpiresAt) {
+		return nil, ErrLoginCodeInvalid
+	}
+
+	// 使用条件更新标记已使用，防止并发请求重复换取
+	result := config.DB.Model(&models.LoginCode{}).
+		Where("id = ? AND used_at IS NULL", code.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrLoginCodeInvalid
+	}
+
+	return GetUserByID(code.UserID)
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OAuthStateTTL 登录状态有效期
+const OAuthStateTTL = 10 * time.Minute
+
+// 登录状态校验错误
+var (
+	ErrStateInvalid  = errors.New("登录状态无效")
+	ErrStateExpired  = errors.New("登录状态已过期，请重新登录")
+	ErrStateReplayed = errors.New("登录状态已被使用，请重新登录")
+)
+
+// CreateOAuthState 生成带签名的一次性登录状态，并绑定提供商、登录后的返回路径、PKCE校验码和OIDC nonce
+func CreateOAuthState(provider string, returnPath string) (*models.OAuthState, error) {
+	nonce, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	// 32字节随机数编码后为43个字符，满足PKCE对code_verifier长度的要求
+	codeVerifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	oidcNonce, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	state := &models.OAuthState{
+		State:        nonce + "." + signState(nonce),
+		Provider:     provider,
+		ReturnPath:   SanitizeReturnPath(returnPath),
+		CodeVerifier: codeVerifier,
+		Nonce:        oidcNonce,
+		ExpiresAt:    now.Add(OAuthStateTTL),
+	}
+	if err := config.DB.Create(state).Error; err != nil {
+		return nil, err
+	}
+
+	// 顺便清理早已过期的状态
+	config.DB.Where("expires_at < ?", now.Add(-OAuthStateTTL)).Delete(&models.OAuthState{})
+
+	return state, nil
+}
+
+// ConsumeOAuthState 校验并消费登录状态，每个状态只能使用一次，且必须由发起登录的提供商回调
+func ConsumeOAuthState(provider string, value string) (*models.OAuthState, error) {
+	// 先校验签名，伪造的state无需查询数据库
+	nonce, sig, ok := strings.Cut(value, ".")
+	if !ok || nonce == "" || !hmac.Equal([]byte(sig), []byte(signState(nonce))) {
+		return nil, ErrStateInvalid
+	}
+
+	var state models.OAuthState
+	if err := config.DB.Where("state = ? AND provider = ?", value, provider).First(&state).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStateInvalid
+		}
+		return nil, err
+	}
+
+	if state.UsedAt != nil {
+		return nil, ErrStateReplayed
+	}
+	now := time.Now()
+	if now.After(state.ExpiresAt) {
+		return nil, ErrStateExpired
+	}
+
+	// 使用条件更新标记已使用，防止并发回调重复消费
+	result := config.DB.Model(&models.OAuthState{}).
+		Where("id = ? AND used_at IS NULL", state.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrStateReplayed
+	}
+	state.UsedAt = &now
+
+	return &state, nil
+}
+
+// SanitizeReturnPath 只允许站内相对路径，防止开放重定向
+func SanitizeReturnPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
+
+// signState 使用JWT密钥对state进行HMAC签名
+func signState(nonce string) string {
+	mac := hmac.New(sha256.New, []byte(config.JWTSecret))
+	mac.Write([]byte("oauth_state:" + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// randomString 生成URL安全的随机字符串
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
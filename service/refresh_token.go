@@ -0,0 +1,125 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 刷新令牌校验错误
+var (
+	ErrRefreshTokenInvalid = errors.New("无效的刷新令牌")
+	ErrRefreshTokenExpired = errors.New("刷新令牌已过期，请重新登录")
+	ErrRefreshTokenReused  = errors.New("刷新令牌已被使用，请重新登录")
+)
+
+// RotateRefreshToken 使用刷新令牌换取新的刷新令牌，返回用户和所属会话
+//
+// 每个刷新令牌只能使用一次，已轮换的令牌再次出现时，
+// 视为令牌被盗用，吊销整个令牌家族及其会话。
+func RotateRefreshToken(raw string) (*models.User, *models.Session, string, error) {
+	var token models.RefreshToken
+	if err := config.DB.Where("token_hash = ?", hashToken(raw)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, "", ErrRefreshTokenInvalid
+		}
+		return nil, nil, "", err
+	}
+
+	if token.UsedAt != nil {
+		log.Printf("检测到刷新令牌重用，吊销会话: user=%d session=%s", token.UserID, token.FamilyID)
+		if err := RevokeSession(token.FamilyID); err != nil {
+			return nil, nil, "", err
+		}
+		return nil, nil, "", ErrRefreshTokenReused
+	}
+	if token.RevokedAt != nil {
+		return nil, nil, "", ErrSessionRevoked
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, nil, "", ErrRefreshTokenExpired
+	}
+
+	// 令牌家族所属的会话必须仍然有效
+	session, err := ValidateSession(token.FamilyID)
+	if err != nil {
+		return nil, nil, "", ErrRefreshTokenInvalid
+	}
+
+	user, err := GetUserByID(token.UserID)
+	if err != nil {
+		return nil, nil, "", ErrRefreshTokenInvalid
+	}
+
+	var newRaw string
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// 使用条件更新标记已轮换，并发请求中只有一个能成功
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", token.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		// 轮换时延长会话有效期
+		session.ExpiresAt = time.Now().Add(config.RefreshTokenExpire)
+		if err := tx.Model(session).Update("expires_at", session.ExpiresAt).Error; err != nil {
+			return err
+		}
+
+		var err error
+		newRaw, err = issueRefreshToken(tx, token.UserID, token.FamilyID)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if err := RevokeSession(token.FamilyID); err != nil {
+			return nil, nil, "", err
+		}
+		return nil, nil, "", ErrRefreshTokenReused
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return user, session, newRaw, nil
+}
+
+// CleanupRefreshTokens 删除过期的刷新令牌
+func CleanupRefreshTokens() (int64, error) {
+	result := config.DB.Where("expires_at < ?", time.Now()).Delete(&models.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
+
+// issueRefreshToken 在指定的数据库事务中签发刷新令牌，familyID为所属会话的SID
+func issueRefreshToken(tx *gorm.DB, userID uint, familyID string) (string, error) {
+	raw, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	token := &models.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashToken(raw),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(config.RefreshTokenExpire),
+	}
+	if err := tx.Create(token).Error; err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// hashToken 计算令牌的SHA-256哈希
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -1 +1,8 @@
 package service
+
+import (
+	"errors"
+)
+
+// ErrInvalidInput 请求参数校验失败，具体原因包含在包装后的错误信息中
+var ErrInvalidInput = errors.New("参数错误")
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+
+	"go-nextjs/config"
+	"go-nextjs/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupTestDB 使用内存数据库替换config.DB，并迁移全部数据表
+func setupTestDB(t *testing.T) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接独立
+	t.Cleanup(func() { sqlDB.Close() })
+
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.OAuthState{},
+		&models.LoginCode{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.Deal{},
+		&models.Source{},
+		&models.DealPriceHistory{},
+		&models.DealEvent{},
+		&models.DealMergeLog{},
+		&models.ExchangeRate{},
+		&models.AICache{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.DB = db
+	config.BaseCurrency = "USD"
+	config.AIAPIKey = ""
+
+	// 清除其他测试缓存的汇率
+	ratesMu.Lock()
+	rates = nil
+	ratesMu.Unlock()
+}
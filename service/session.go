@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sessionTouchInterval 更新会话最后活跃时间的最小间隔，避免每个请求都写库
+const sessionTouchInterval = time.Minute
+
+// 会话校验错误
+var (
+	ErrSessionInvalid = errors.New("会话不存在")
+	ErrSessionRevoked = errors.New("会话已失效，请重新登录")
+)
+
+// SessionMeta 创建会话时记录的客户端信息
+type SessionMeta struct {
+	IP        string
+	UserAgent string
+}
+
+// StartSession 创建登录会话并签发该会话的第一个刷新令牌
+func StartSession(userID uint, meta SessionMeta) (*models.Session, string, error) {
+	sid, err := randomString(24)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		SID:        sid,
+		UserID:     userID,
+		IP:         meta.IP,
+		UserAgent:  truncate(meta.UserAgent, 500),
+		Device:     parseDevice(meta.UserAgent),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(config.RefreshTokenExpire),
+	}
+
+	var refreshToken string
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		var err error
+		refreshToken, err = issueRefreshToken(tx, userID, sid)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return session, refreshToken, nil
+}
+
+// ValidateSession 校验会话是否有效，并按间隔更新最后活跃时间
+func ValidateSession(sid string) (*models.Session, error) {
+	if sid == "" {
+		return nil, ErrSessionInvalid
+	}
+
+	var session models.Session
+	if err := config.DB.Where("sid = ?", sid).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionInvalid
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return nil, ErrSessionRevoked
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		config.DB.Model(&session).Update("last_seen_at", now)
+	}
+	return &session, nil
+}
+
+// ListUserSessions 获取用户的有效会话
+func ListUserSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := config.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession 吊销会话及其全部刷新令牌
+func RevokeSession(sid string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.Session{}).
+			Where("sid = ? AND revoked_at IS NULL", sid).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", sid).
+			Update("revoked_at", now).Error
+	})
+}
+
+// RevokeSessionByID 根据ID吊销会话，userID不为0时只允许吊销该用户自己的会话
+func RevokeSessionByID(id uint, userID uint) error {
+	query := config.DB.Where("id = ?", id)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var session models.Session
+	if err := query.First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionInvalid
+		}
+		return err
+	}
+	return RevokeSession(session.SID)
+}
+
+// RevokeUserSessions 吊销用户的全部会话，返回吊销的会话数量
+func RevokeUserSessions(userID uint) (int64, error) {
+	var revoked int64
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		revoked = result.RowsAffected
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+	return revoked, err
+}
+
+// CleanupSessions 删除过期或已吊销超过保留期的会话
+func CleanupSessions(retention time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-retention)
+	result := config.DB.
+		Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).
+		Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
+
+// parseDevice 根据User-Agent粗略识别设备和浏览器
+func parseDevice(ua string) string {
+	if ua == "" {
+		return ""
+	}
+
+	os := "未知系统"
+	switch {
+	case strings.Contains(ua, "iPhone"):
+		os = "iPhone"
+	case strings.Contains(ua, "iPad"):
+		os = "iPad"
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "Mac OS X"), strings.Contains(ua, "Macintosh"):
+		os = "macOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+
+	browser := "未知浏览器"
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	return os + " / " + browser
+}
+
+// truncate 截断字符串到指定字节数以内
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
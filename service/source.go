@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"go-nextjs/pkg/feed"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 同步间隔的范围（分钟）
+const (
+	defaultSourceInterval = 60
+	minSourceInterval     = 1
+	maxSourceInterval     = 7 * 24 * 60
+)
+
+// SourceInput 创建或更新数据来源的参数
+type SourceInput struct {
+	Name     string `json:"name" binding:"required"`
+	Type     string `json:"type" binding:"required"`
+	URL      string `json:"url" binding:"required"`
+	Provider string `json:"provider"`
+	Interval int    `json:"interval"` // 同步间隔（分钟），默认60
+	Enabled  *bool  `json:"enabled"`  // 默认启用
+
+	Selectors models.SelectorRule `json:"selectors"` // HTML来源的选择器规则
+}
+
+// ListSources 获取所有数据来源
+func ListSources() ([]models.Source, error) {
+	var sources []models.Source
+	if err := config.DB.Order("id").Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// GetSource 根据ID获取数据来源
+func GetSource(id uint) (*models.Source, error) {
+	var source models.Source
+	if err := config.DB.First(&source, id).Error; err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// CreateSource 创建数据来源，创建后在下一次定时检查时立即同步
+func CreateSource(input *SourceInput) (*models.Source, error) {
+	source := &models.Source{Enabled: true, NextSyncAt: time.Now()}
+	if err := applySourceInput(source, input); err != nil {
+		return nil, err
+	}
+	if err := config.DB.Create(source).Error; err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// UpdateSource 更新数据来源
+func UpdateSource(id uint, input *SourceInput) (*models.Source, error) {
+	source, err := GetSource(id)
+	if err != nil {
+		return nil, err
+	}
+
+	oldURL, oldSelectors := source.URL, source.Selectors
+	if err := applySourceInput(source, input); err != nil {
+		return nil, err
+	}
+	// 地址或规则变化后立即完整地重新同步，并清除之前的错误状态
+	if source.URL != oldURL || source.Selectors != oldSelectors {
+		source.NextSyncAt = time.Now()
+		source.LastError = ""
+		source.ErrorCount = 0
+		source.ETag = ""
+		source.LastModified = ""
+	}
+
+	if err := config.DB.Save(source).Error; err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// DeleteSource 删除数据来源，已抓取的优惠保留
+func DeleteSource(id uint) error {
+	result := config.DB.Delete(&models.Source{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// applySourceInput 校验参数并写入数据来源
+func applySourceInput(source *models.Source, input *SourceInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return fmt.Errorf("%w: 名称不能为空", ErrInvalidInput)
+	}
+
+	sourceType := strings.ToLower(strings.TrimSpace(input.Type))
+	if !feed.Supported(sourceType) {
+		return fmt.Errorf("%w: 不支持的来源类型 %s，可选 %s", ErrInvalidInput, input.Type, strings.Join(feed.Types(), "、"))
+	}
+
+	rawURL := strings.TrimSpace(input.URL)
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: 无效的抓取地址", ErrInvalidInput)
+	}
+
+	selectors := trimSelectors(input.Selectors)
+	if sourceType == models.SourceTypeHTML {
+		if err := feed.ValidateSelectors(&selectors); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+	}
+
+	interval := input.Interval
+	if interval == 0 {
+		interval = defaultSourceInterval
+	}
+	if interval < minSourceInterval || interval > maxSourceInterval {
+		return fmt.Errorf("%w: 同步间隔需在%d到%d分钟之间", ErrInvalidInput, minSourceInterval, maxSourceInterval)
+	}
+
+	source.Name = name
+	source.Type = sourceType
+	source.URL = rawURL
+	source.Provider = strings.TrimSpace(input.Provider)
+	source.Interval = interval
+	if input.Enabled != nil {
+		source.Enabled = *input.Enabled
+	}
+	source.Selectors = selectors
+	return nil
+}
+
+// trimSelectors 去除选择器两端的空白
+func trimSelectors(rule models.SelectorRule) models.SelectorRule {
+	return models.SelectorRule{
+		Item:        strings.TrimSpace(rule.Item),
+		Title:       strings.TrimSpace(rule.Title),
+		Price:       strings.TrimSpace(rule.Price),
+		Link:        strings.TrimSpace(rule.Link),
+		Description: strings.TrimSpace(rule.Description),
+	}
+}
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"go-nextjs/pkg/ai"
+	"go-nextjs/pkg/feed"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// syncConcurrency 同时同步的来源数量
+	syncConcurrency = 4
+	// syncTimeout 单个来源的同步超时，新条目需要AI分析，留出足够时间
+	syncTimeout = 10 * time.Minute
+	// maxBackoffShift 连续失败时同步间隔最多放大到 2^maxBackoffShift 倍
+	maxBackoffShift = 3
+	// maxPreviewItems 预览返回的最大条目数
+	maxPreviewItems = 50
+	// maxEnrichAttempts AI分析失败后最多尝试的次数，之后保留原始信息不再重试
+	maxEnrichAttempts = 3
+)
+
+// ErrSourceSyncing 来源正在同步中
+var ErrSourceSyncing = errors.New("该来源正在同步中")
+
+// ErrSourceFetch 抓取来源失败
+var ErrSourceFetch = errors.New("抓取失败")
+
+// errItemSkipped 条目无需写入
+var errItemSkipped = errors.New("条目已跳过")
+
+// syncing 正在同步的来源ID，防止定时任务和手动同步重叠
+var syncing sync.Map
+
+// SyncReport 单次同步的结果统计
+type SyncReport struct {
+	Fetched int `json:"fetched"` // 抓取到的条目数
+	Created int `json:"created"` // 新增的优惠数
+	Updated int `json:"updated"` // 更新的优惠数
+	Skipped int `json:"skipped"` // 跳过的条目数
+
+	NotModified bool `json:"not_modified"` // 来源内容未变化
+}
+
+// SyncDueSources 同步所有已到期的启用来源
+func SyncDueSources(ctx context.Context) error {
+	var sources []models.Source
+	err := config.DB.Where("enabled = ? AND next_sync_at <= ?", true, time.Now()).
+		Order("next_sync_at").Find(&sources).Error
+	if err != nil {
+		return err
+	}
+
+	sem := make(chan struct{}, syncConcurrency)
+	var wg sync.WaitGroup
+	for i := range sources {
+		source := &sources[i]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			report, err := syncSource(ctx, source)
+			if errors.Is(err, ErrSourceSyncing) {
+				return
+			}
+			if err != nil {
+				log.Printf("同步来源 %s 失败: %v", source.Name, err)
+				return
+			}
+			log.Printf("同步来源 %s 完成: 抓取%d 新增%d 更新%d 跳过%d",
+				source.Name, report.Fetched, report.Created, report.Updated, report.Skipped)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// SyncSource 立即同步指定来源
+func SyncSource(ctx context.Context, id uint) (*SyncReport, error) {
+	source, err := GetSource(id)
+	if err != nil {
+		return nil, err
+	}
+	return syncSource(ctx, source)
+}
+
+// syncSource 抓取来源并写入优惠，记录同步状态
+func syncSource(ctx context.Context, source *models.Source) (*SyncReport, error) {
+	if _, running := syncing.LoadOrStore(source.ID, struct{}{}); running {
+		return nil, ErrSourceSyncing
+	}
+	defer syncing.Delete(source.ID)
+
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	report, err := fetchAndUpsert(ctx, source)
+	if stateErr := recordSyncResult(source, report, err); stateErr != nil {
+		log.Printf("保存来源 %s 的同步状态失败: %v", source.Name, stateErr)
+	}
+	return report, err
+}
+
+// fetchAndUpsert 抓取来源并逐条写入优惠
+func fetchAndUpsert(ctx context.Context, source *models.Source) (*SyncReport, error) {
+	req := &feed.Request{
+		Type:      source.Type,
+		URL:       source.URL,
+		Selectors: source.Selectors,
+	}
+	// 有等待重新分析的优惠时不使用条件请求，确保能重新拿到这些条目
+	pending, err := hasPendingEnrichment(source.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !pending {
+		req.ETag = source.ETag
+		req.LastModified = source.LastModified
+	}
+
+	result, err := feed.Fetch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	source.ETag = result.ETag
+	source.LastModified = result.LastModified
+
+	report := &SyncReport{Fetched: len(result.Items), NotModified: result.NotModified}
+	for i := range result.Items {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		created, err := upsertSourceItem(ctx, source, &result.Items[i])
+		switch {
+		case errors.Is(err, errItemSkipped):
+			report.Skipped++
+		case err != nil:
+			log.Printf("写入来源 %s 的条目 %s 失败: %v", source.Name, result.Items[i].ExternalID, err)
+			report.Skipped++
+		case created:
+			report.Created++
+		default:
+			report.Updated++
+		}
+	}
+	return report, nil
+}
+
+// upsertSourceItem 按来源和条目ID创建或更新优惠，返回是否为新建
+func upsertSourceItem(ctx context.Context, source *models.Source, item *feed.Item) (bool, error) {
+	if item.ExternalID == "" || item.Title == "" {
+		return false, errItemSkipped
+	}
+
+	input := &DealInput{
+		Title:        item.Title,
+		Provider:     item.Provider,
+		Price:        item.Price,
+		Currency:     item.Currency,
+		BillingCycle: item.BillingCycle,
+		URL:          item.URL,
+		VPSConfig:    item.VPSConfig,
+		EndsAt:       item.EndsAt,
+	}
+	if input.Provider == "" {
+		input.Provider = source.Provider
+	}
+
+	deal, err := findSourceDeal(source.ID, item)
+	if err != nil {
+		return false, err
+	}
+	created := deal == nil
+
+	// 管理员删除过的优惠不再恢复
+	if !created && deal.DeletedAt.Valid {
+		return false, errItemSkipped
+	}
+	// 需要AI分析的条目只在新建时分析，之后只同步价格；上次分析失败的重新分析
+	if !created && item.Enrich && !deal.EnrichPending {
+		return false, updateDealPrice(deal, item)
+	}
+	enriched := true
+	if item.Enrich {
+		if enriched, err = enrichItem(ctx, input, item); err != nil {
+			return false, err
+		}
+	}
+	if created {
+		deal = &models.Deal{}
+		if err := applyDealInput(deal, input); err != nil {
+			return false, err
+		}
+		if item.Enrich {
+			recordEnrichAttempt(deal, enriched)
+		}
+		deal.SourceID = &source.ID
+		deal.ExternalID = item.ExternalID
+		if err := saveDeal(deal, nil); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	old := *deal
+	if err := applyDealInput(deal, input); err != nil {
+		return false, err
+	}
+	if item.Enrich {
+		recordEnrichAttempt(deal, enriched)
+	}
+	return false, saveDeal(deal, &old)
+}
+
+// recordEnrichAttempt 记录一次AI分析，未完成且未超过尝试次数时等待下次同步重试
+func recordEnrichAttempt(deal *models.Deal, enriched bool) {
+	deal.EnrichAttempts++
+	deal.EnrichPending = !enriched && deal.EnrichAttempts < maxEnrichAttempts
+	if !enriched && !deal.EnrichPending {
+		log.Printf("条目 %s 的AI分析已失败%d次，不再重试", deal.ExternalID, deal.EnrichAttempts)
+	}
+}
+
+// hasPendingEnrichment 来源中是否有等待重新分析的优惠
+func hasPendingEnrichment(sourceID uint) (bool, error) {
+	var count int64
+	err := config.DB.Model(&models.Deal{}).
+		Where("source_id = ? AND enrich_pending = ?", sourceID, true).
+		Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// updateDealPrice 只更新已存在优惠的价格，价格未解析出或未变化时跳过
+func updateDealPrice(deal *models.Deal, item *feed.Item) error {
+	if item.Price <= 0 {
+		return errItemSkipped
+	}
+
+	input := &DealInput{
+		Title:        deal.Title,
+		Provider:     deal.Provider,
+		Price:        item.Price,
+		Currency:     item.Currency,
+		BillingCycle: item.BillingCycle,
+		URL:          deal.URL,
+		VPSConfig: ai.VPSConfig{
+			CPU:       deal.CPU,
+			RAM:       deal.RAM,
+			Disk:      deal.Disk,
+			Bandwidth: deal.Bandwidth,
+			IP:        deal.IP,
+			Location:  deal.Location,
+			Remark:    deal.Remark,
+		},
+	}
+	if input.Currency == "" {
+		input.Currency = deal.Currency
+	}
+	if input.BillingCycle == "" {
+		input.BillingCycle = deal.BillingCycle
+	}
+
+	old := *deal
+	if err := applyDealInput(deal, input); err != nil {
+		return err
+	}
+	if !priceChanged(&old, deal) {
+		return errItemSkipped
+	}
+	return saveDeal(deal, &old)
+}
+
+// findSourceDeal 按条目ID查找来源中已存在的优惠（包括已删除的），找不到时按链接查找
+func findSourceDeal(sourceID uint, item *feed.Item) (*models.Deal, error) {
+	// 新条目是常态，使用Find避免记录不存在时输出错误日志
+	var deals []models.Deal
+	err := config.DB.Unscoped().
+		Where("source_id = ? AND external_id = ?", sourceID, item.ExternalID).
+		Limit(1).Find(&deals).Error
+	if err == nil && len(deals) == 0 && item.URL != "" {
+		err = config.DB.Unscoped().
+			Where("source_id = ? AND url = ?", sourceID, item.URL).
+			Limit(1).Find(&deals).Error
+	}
+	if err != nil || len(deals) == 0 {
+		return nil, err
+	}
+	return &deals[0], nil
+}
+
+// enrichItem 使用AI从描述中提取配置并优化标题，失败时保留原始信息，返回是否全部完成
+//
+// 同步被取消时返回ctx的错误，条目不写入，留待下次同步重新分析。
+func enrichItem(ctx context.Context, input *DealInput, item *feed.Item) (bool, error) {
+	if config.AIAPIKey == "" {
+		return true, nil
+	}
+
+	enriched := true
+	if item.Description != "" {
+		cfg, err := ai.ParseVPSDescriptionContext(ctx, item.Description)
+		if err != nil {
+			log.Printf("AI提取配置失败 %s: %v", item.ExternalID, err)
+			enriched = false
+		} else {
+			input.VPSConfig = *cfg
+		}
+	}
+
+	title, err := ai.OptimizeTitleContext(ctx, item.Title)
+	if err != nil {
+		log.Printf("AI优化标题失败 %s: %v", item.ExternalID, err)
+		enriched = false
+	} else {
+		input.Title = title
+	}
+	return enriched, ctx.Err()
+}
+
+// PreviewSource 按参数试抓取来源，不写入数据库也不调用AI，用于调试抓取规则
+func PreviewSource(ctx context.Context, input *SourceInput) ([]feed.Item, error) {
+	source := &models.Source{}
+	if err := applySourceInput(source, input); err != nil {
+		return nil, err
+	}
+	return previewSource(ctx, source)
+}
+
+// PreviewStoredSource 按已保存的规则试抓取来源
+func PreviewStoredSource(ctx context.Context, id uint) ([]feed.Item, error) {
+	source, err := GetSource(id)
+	if err != nil {
+		return nil, err
+	}
+	return previewSource(ctx, source)
+}
+
+// previewSource 抓取来源的条目，忽略条件请求缓存
+func previewSource(ctx context.Context, source *models.Source) ([]feed.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	result, err := feed.Fetch(ctx, &feed.Request{
+		Type:      source.Type,
+		URL:       source.URL,
+		Selectors: source.Selectors,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSourceFetch, err)
+	}
+
+	items := result.Items
+	for i := range items {
+		if items[i].Provider == "" {
+			items[i].Provider = source.Provider
+		}
+	}
+	if len(items) > maxPreviewItems {
+		items = items[:maxPreviewItems]
+	}
+	return items, nil
+}
+
+// recordSyncResult 保存同步状态，失败时按连续失败次数延后下次同步
+func recordSyncResult(source *models.Source, report *SyncReport, syncErr error) error {
+	now := time.Now()
+	interval := time.Duration(source.Interval) * time.Minute
+
+	updates := map[string]interface{}{
+		"last_sync_at": now,
+	}
+	if syncErr != nil {
+		errorCount := source.ErrorCount + 1
+		shift := errorCount
+		if shift > maxBackoffShift {
+			shift = maxBackoffShift
+		}
+		updates["last_error"] = syncErr.Error()
+		updates["error_count"] = errorCount
+		updates["next_sync_at"] = now.Add(interval << shift)
+	} else {
+		updates["last_error"] = ""
+		updates["error_count"] = 0
+		updates["etag"] = source.ETag
+		updates["last_modified"] = source.LastModified
+		updates["next_sync_at"] = now.Add(interval)
+		if !report.NotModified {
+			updates["item_count"] = report.Fetched
+		}
+	}
+
+	return config.DB.Model(source).Updates(updates).Error
+}
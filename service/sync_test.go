@@ -0,0 +1,459 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"go-nextjs/pkg/ai"
+)
+
+const testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel>
+<item><title>RackNerd 1GB KVM $10.99/year</title><link>https://forum.test/t/1</link><guid>rss-1</guid>
+<description>&lt;p&gt;1 vCPU, 1GB RAM, 20GB SSD&lt;/p&gt;</description></item>
+<item><title>BuyVM 2GB Slice $7/mo</title><link>https://forum.test/t/2</link><guid>rss-2</guid>
+<description>2GB RAM</description></item>
+</channel></rss>`
+
+const testAtom = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+<entry><id>atom-1</id><title>Hetzner CX22 €4.35/mo</title>
+<link rel="alternate" href="https://blog.test/cx22"/><summary>2 vCPU 4GB</summary></entry>
+</feed>`
+
+const testJSON = `{"deals": [
+{"id": 101, "title": "Vultr 1GB", "provider": "Vultr", "price": "6", "currency": "usd",
+ "billing_cycle": "monthly", "url": "https://vultr.test/1", "cpu": "1 vCPU", "ram": "1GB", "location": "Tokyo"},
+{"id": "102", "title": "Vultr 2GB", "provider": "Vultr", "price": 12, "url": "https://vultr.test/2", "ram": "2GB"}
+]}`
+
+const testHTML = `<html><body>
+<div class="plan"><h3>Starter</h3><span class="price">$3.50/mo</span><a href="/order/1">Order</a></div>
+<div class="plan"><h3>Pro</h3><span class="price">$48 / year</span><a href="/order/2">Order</a></div>
+<div class="plan"><span class="price">$1</span></div>
+</body></html>`
+
+// feedServer 模拟各类来源，记录条件请求头
+type feedServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	bodies      map[string]string // 路径对应的响应体
+	ifNoneMatch map[string]string // 各路径最近一次收到的If-None-Match
+}
+
+func newFeedServer(t *testing.T) *feedServer {
+	t.Helper()
+	f := &feedServer{
+		bodies: map[string]string{
+			"/rss":  testRSS,
+			"/atom": testAtom,
+			"/json": testJSON,
+			"/html": testHTML,
+		},
+		ifNoneMatch: map[string]string{},
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		body, ok := f.bodies[r.URL.Path]
+		f.ifNoneMatch[r.URL.Path] = r.Header.Get("If-None-Match")
+		f.mu.Unlock()
+
+		if r.URL.Path == "/broken" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		// ETag由内容决定，内容未变化时返回304
+		etag := fmt.Sprintf(`"%x"`, len(body))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// set 替换路径的响应体
+func (f *feedServer) set(path, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bodies[path] = body
+}
+
+// conditional 返回路径最近一次收到的If-None-Match
+func (f *feedServer) conditional(path string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ifNoneMatch[path]
+}
+
+// createSource 创建一个已到期的启用来源
+func createSource(t *testing.T, name, sourceType, url string, selectors models.SelectorRule) *models.Source {
+	t.Helper()
+	source := &models.Source{
+		Name:       name,
+		Type:       sourceType,
+		URL:        url,
+		Provider:   "Default " + name,
+		Interval:   60,
+		Enabled:    true,
+		NextSyncAt: time.Now().Add(-time.Minute),
+		Selectors:  selectors,
+	}
+	if err := config.DB.Create(source).Error; err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+// sourceDeals 按条目ID返回来源的优惠
+func sourceDeals(t *testing.T, sourceID uint) map[string]models.Deal {
+	t.Helper()
+	var deals []models.Deal
+	if err := config.DB.Where("source_id = ?", sourceID).Find(&deals).Error; err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[string]models.Deal, len(deals))
+	for _, d := range deals {
+		byID[d.ExternalID] = d
+	}
+	return byID
+}
+
+// reloadSource 读取来源的最新同步状态
+func reloadSource(t *testing.T, id uint) *models.Source {
+	t.Helper()
+	source, err := GetSource(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+// makeDue 将来源的下次同步时间改为已到期
+func makeDue(t *testing.T, ids ...uint) {
+	t.Helper()
+	err := config.DB.Model(&models.Source{}).Where("id IN ?", ids).
+		Update("next_sync_at", time.Now().Add(-time.Minute)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncDueSourcesFeedTypes(t *testing.T) {
+	setupTestDB(t)
+	server := newFeedServer(t)
+
+	rss := createSource(t, "rss", models.SourceTypeRSS, server.URL+"/rss", models.SelectorRule{})
+	atom := createSource(t, "atom", models.SourceTypeRSS, server.URL+"/atom", models.SelectorRule{})
+	jsonSource := createSource(t, "json", models.SourceTypeJSON, server.URL+"/json", models.SelectorRule{})
+	html := createSource(t, "html", models.SourceTypeHTML, server.URL+"/html", models.SelectorRule{
+		Item:  ".plan",
+		Title: "h3",
+		Price: ".price",
+		Link:  "a",
+	})
+
+	if err := SyncDueSources(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	type want struct {
+		title, provider, currency, cycle, url string
+		price                                 float64
+	}
+	tests := []struct {
+		source *models.Source
+		deals  map[string]want
+	}{
+		{rss, map[string]want{
+			"rss-1": {"RackNerd 1GB KVM $10.99/year", "Default rss", "USD", models.BillingAnnually, "https://forum.test/t/1", 10.99},
+			"rss-2": {"BuyVM 2GB Slice $7/mo", "Default rss", "USD", models.BillingMonthly, "https://forum.test/t/2", 7},
+		}},
+		{atom, map[string]want{
+			"atom-1": {"Hetzner CX22 €4.35/mo", "Default atom", "EUR", models.BillingMonthly, "https://blog.test/cx22", 4.35},
+		}},
+		{jsonSource, map[string]want{
+			"101": {"Vultr 1GB", "Vultr", "USD", models.BillingMonthly, "https://vultr.test/1", 6},
+			"102": {"Vultr 2GB", "Vultr", "USD", models.BillingMonthly, "https://vultr.test/2", 12},
+		}},
+		{html, map[string]want{
+			server.URL + "/order/1": {"Starter", "Default html", "USD", models.BillingMonthly, server.URL + "/order/1", 3.5},
+			server.URL + "/order/2": {"Pro", "Default html", "USD", models.BillingAnnually, server.URL + "/order/2", 48},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source.Name, func(t *testing.T) {
+			deals := sourceDeals(t, tt.source.ID)
+			if len(deals) != len(tt.deals) {
+				t.Fatalf("deals = %d, want %d", len(deals), len(tt.deals))
+			}
+			for id, w := range tt.deals {
+				d, ok := deals[id]
+				if !ok {
+					t.Errorf("missing deal %s", id)
+					continue
+				}
+				if d.Title != w.title || d.Provider != w.provider || d.Currency != w.currency ||
+					d.BillingCycle != w.cycle || d.URL != w.url || d.Price != w.price {
+					t.Errorf("deal %s = {%q %q %s %s %s %v}, want %+v",
+						id, d.Title, d.Provider, d.Currency, d.BillingCycle, d.URL, d.Price, w)
+				}
+			}
+
+			source := reloadSource(t, tt.source.ID)
+			if source.LastError != "" || source.ErrorCount != 0 || source.ETag == "" || source.LastSyncAt == nil {
+				t.Errorf("source state = %+v", source)
+			}
+			if !source.NextSyncAt.After(time.Now().Add(59 * time.Minute)) {
+				t.Errorf("next_sync_at = %v, want about an hour later", source.NextSyncAt)
+			}
+		})
+	}
+
+	// JSON来源提供了结构化配置，直接写入
+	if d := sourceDeals(t, jsonSource.ID)["101"]; d.CPU != "1 vCPU" || d.RAMMB != 1024 || d.Location != "Tokyo" {
+		t.Errorf("json deal config = %q %d %q", d.CPU, d.RAMMB, d.Location)
+	}
+}
+
+func TestSyncDueSourcesConditionalRequests(t *testing.T) {
+	setupTestDB(t)
+	server := newFeedServer(t)
+	source := createSource(t, "json", models.SourceTypeJSON, server.URL+"/json", models.SelectorRule{})
+
+	if err := SyncDueSources(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.conditional("/json"); got != "" {
+		t.Errorf("first request If-None-Match = %q, want empty", got)
+	}
+	first := reloadSource(t, source.ID)
+	if first.ETag == "" || first.ItemCount != 2 {
+		t.Fatalf("source after first sync = %+v", first)
+	}
+
+	// 内容未变化：带上ETag发送条件请求，收到304后不写入任何优惠，也不清空条目数
+	makeDue(t, source.ID)
+	if err := SyncDueSources(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.conditional("/json"); got != first.ETag {
+		t.Errorf("second request If-None-Match = %q, want %q", got, first.ETag)
+	}
+	second := reloadSource(t, source.ID)
+	if second.ETag != first.ETag || second.ItemCount != 2 || second.LastError != "" {
+		t.Errorf("source after 304 = %+v", second)
+	}
+	if !second.NextSyncAt.After(time.Now()) {
+		t.Errorf("next_sync_at not advanced after 304")
+	}
+	if deals := sourceDeals(t, source.ID); len(deals) != 2 {
+		t.Errorf("deals after 304 = %d, want 2", len(deals))
+	}
+
+	// 内容变化：ETag随之更新，已有优惠的价格被更新
+	server.set("/json", `[{"id": 101, "title": "Vultr 1GB", "provider": "Vultr", "price": 5, "url": "https://vultr.test/1", "ram": "1GB"}]`)
+	makeDue(t, source.ID)
+	if err := SyncDueSources(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	third := reloadSource(t, source.ID)
+	if third.ETag == first.ETag || third.ItemCount != 1 {
+		t.Errorf("source after change = %+v", third)
+	}
+	if d := sourceDeals(t, source.ID)["101"]; d.Price != 5 {
+		t.Errorf("price after change = %v, want 5", d.Price)
+	}
+}
+
+func TestSyncDueSourcesIsolatesFailures(t *testing.T) {
+	setupTestDB(t)
+	server := newFeedServer(t)
+
+	broken := createSource(t, "broken", models.SourceTypeRSS, server.URL+"/broken", models.SelectorRule{})
+	missing := createSource(t, "missing", models.SourceTypeJSON, server.URL+"/missing", models.SelectorRule{})
+	invalid := createSource(t, "invalid", models.SourceTypeRSS, server.URL+"/json", models.SelectorRule{})
+	healthy := createSource(t, "healthy", models.SourceTypeRSS, server.URL+"/rss", models.SelectorRule{})
+	later := createSource(t, "later", models.SourceTypeJSON, server.URL+"/json", models.SelectorRule{})
+	config.DB.Model(later).Update("next_sync_at", time.Now().Add(time.Hour))
+
+	if err := SyncDueSources(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 其他来源失败不影响正常来源
+	if deals := sourceDeals(t, healthy.ID); len(deals) != 2 {
+		t.Errorf("healthy deals = %d, want 2", len(deals))
+	}
+	if s := reloadSource(t, healthy.ID); s.ErrorCount != 0 || s.LastError != "" {
+		t.Errorf("healthy source = %+v", s)
+	}
+
+	// 失败的来源记录错误，并按退避策略延后下次同步
+	for _, source := range []*models.Source{broken, missing, invalid} {
+		s := reloadSource(t, source.ID)
+		if s.ErrorCount != 1 || s.LastError == "" || s.ETag != "" {
+			t.Errorf("%s source = %+v", source.Name, s)
+		}
+		if !s.NextSyncAt.After(time.Now().Add(time.Hour + 59*time.Minute)) {
+			t.Errorf("%s next_sync_at = %v, want backoff of two intervals", source.Name, s.NextSyncAt)
+		}
+		if deals := sourceDeals(t, source.ID); len(deals) != 0 {
+			t.Errorf("%s deals = %d, want 0", source.Name, len(deals))
+		}
+	}
+
+	// 未到期的来源不同步
+	if s := reloadSource(t, later.ID); s.LastSyncAt != nil {
+		t.Errorf("source not due was synced")
+	}
+
+	// 连续失败时退避倍数继续增大，恢复后清空错误
+	makeDue(t, broken.ID)
+	if err := SyncDueSources(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := reloadSource(t, broken.ID); s.ErrorCount != 2 || !s.NextSyncAt.After(time.Now().Add(3*time.Hour+59*time.Minute)) {
+		t.Errorf("broken source after second failure = %+v", s)
+	}
+
+	config.DB.Model(broken).Update("url", server.URL+"/rss")
+	makeDue(t, broken.ID)
+	if err := SyncDueSources(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := reloadSource(t, broken.ID); s.ErrorCount != 0 || s.LastError != "" {
+		t.Errorf("broken source after recovery = %+v", s)
+	}
+}
+
+// fakeAI 模拟AI服务，整个测试进程共享一个，因为ai包的默认客户端只按首次调用时的配置创建
+var fakeAI struct {
+	once   sync.Once
+	server *httptest.Server
+
+	mu        sync.Mutex
+	failTitle bool // 优化标题的请求返回错误
+}
+
+// useFakeAI 启用AI并指向模拟服务，提取配置总是成功，优化标题按failTitle决定是否失败
+func useFakeAI(t *testing.T, failTitle bool) {
+	t.Helper()
+	fakeAI.once.Do(func() {
+		fakeAI.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req ai.ChatRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var content string
+			if req.ResponseFormat != nil {
+				content = `{"cpu": "1 Core", "ram": "1GB RAM", "disk": "20GB SSD", "bandwidth": "", "ip": "", "location": "", "remark": ""}`
+			} else {
+				fakeAI.mu.Lock()
+				fail := fakeAI.failTitle
+				fakeAI.mu.Unlock()
+				if fail {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprint(w, `{"error": {"message": "model overloaded", "type": "invalid_request_error"}}`)
+					return
+				}
+				content = "优化 " + strings.TrimPrefix(req.Messages[len(req.Messages)-1].Content, "原标题: ")
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"choices": []interface{}{map[string]interface{}{
+					"message": map[string]string{"role": "assistant", "content": content},
+				}},
+			})
+		}))
+	})
+	setFakeAIFailure(failTitle)
+
+	config.AIURL = fakeAI.server.URL
+	config.AIAPIKey = "test-key"
+	config.AIModel = "test-model"
+	t.Cleanup(func() { config.AIAPIKey = "" })
+}
+
+func setFakeAIFailure(failTitle bool) {
+	fakeAI.mu.Lock()
+	defer fakeAI.mu.Unlock()
+	fakeAI.failTitle = failTitle
+}
+
+func TestSyncRetriesFailedEnrichment(t *testing.T) {
+	setupTestDB(t)
+	useFakeAI(t, true)
+	server := newFeedServer(t)
+	source := createSource(t, "rss", models.SourceTypeRSS, server.URL+"/rss", models.SelectorRule{})
+
+	// 优化标题失败：保留原标题写入，并标记等待重试
+	if err := SyncDueSources(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	d := sourceDeals(t, source.ID)["rss-1"]
+	if d.Title != "RackNerd 1GB KVM $10.99/year" || !d.EnrichPending || d.EnrichAttempts != 1 {
+		t.Fatalf("deal after failed enrichment = %q pending=%v attempts=%d", d.Title, d.EnrichPending, d.EnrichAttempts)
+	}
+	if d.CPU != "1 Core" {
+		t.Errorf("cpu = %q, want config from AI", d.CPU)
+	}
+
+	// 有等待重试的优惠时不发送条件请求，重新分析成功后清除标记
+	setFakeAIFailure(false)
+	makeDue(t, source.ID)
+	if err := SyncDueSources(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.conditional("/rss"); got != "" {
+		t.Errorf("If-None-Match = %q, want empty while enrichment is pending", got)
+	}
+	d = sourceDeals(t, source.ID)["rss-1"]
+	if d.Title != "优化 RackNerd 1GB KVM $10.99/year" || d.EnrichPending || d.EnrichAttempts != 2 {
+		t.Fatalf("deal after retry = %q pending=%v attempts=%d", d.Title, d.EnrichPending, d.EnrichAttempts)
+	}
+
+	// 分析完成后恢复条件请求
+	makeDue(t, source.ID)
+	if err := SyncDueSources(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.conditional("/rss"); got == "" {
+		t.Errorf("If-None-Match empty after enrichment completed")
+	}
+}
+
+func TestSyncStopsRetryingEnrichment(t *testing.T) {
+	setupTestDB(t)
+	useFakeAI(t, true)
+	server := newFeedServer(t)
+	source := createSource(t, "rss", models.SourceTypeRSS, server.URL+"/rss", models.SelectorRule{})
+
+	for i := 1; i <= maxEnrichAttempts; i++ {
+		makeDue(t, source.ID)
+		if err := SyncDueSources(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		d := sourceDeals(t, source.ID)["rss-2"]
+		if d.EnrichAttempts != i || d.EnrichPending != (i < maxEnrichAttempts) {
+			t.Fatalf("sync %d: pending=%v attempts=%d", i, d.EnrichPending, d.EnrichAttempts)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-nextjs/config"
+	"go-nextjs/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrLastAdmin 试图移除最后一个管理员
+var ErrLastAdmin = errors.New("不能移除最后一个管理员")
+
+// OAuthProfile 第三方登录返回的用户资料
+type OAuthProfile struct {
+	Provider    string // 提供商
+	ExternID    string // 提供商侧的用户ID
+	Username    string // 用户名
+	Nickname    string // 昵称
+	Email       string // 邮箱
+	Avatar      string // 头像
+	AccessToken string // 提供商的访问令牌
+
+	EmailVerified bool // 提供商确认邮箱已验证
+}
+
+// UpsertOAuthUser 根据提供商和外部ID创建或更新本地用户
+func UpsertOAuthUser(profile *OAuthProfile) (*models.User, error) {
+	if profile == nil || profile.ExternID == "" || profile.Provider == "" {
+		return nil, fmt.Errorf("用户资料不完整")
+	}
+
+	var user models.User
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("provider = ? AND extern_id = ?", profile.Provider, profile.ExternID).First(&user).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// 已存在的用户只更新资料，角色只在创建时确定，之后由管理员修改
+		if err == nil {
+			return tx.Model(&user).Updates(map[string]interface{}{
+				"nickname":   profile.Nickname,
+				"email":      profile.Email,
+				"avatar":     profile.Avatar,
+				"last_login": time.Now().Unix(),
+				"token":      profile.AccessToken,
+			}).Error
+		}
+
+		// 首次登录，创建用户
+		username, err := uniqueUsername(tx, profile)
+		if err != nil {
+			return err
+		}
+		role, err := bootstrapRole(tx, profile)
+		if err != nil {
+			return err
+		}
+		user = models.User{
+			Username:  username,
+			Role:      role,
+			Nickname:  profile.Nickname,
+			Email:     profile.Email,
+			Avatar:    profile.Avatar,
+			ExternID:  profile.ExternID,
+			Provider:  profile.Provider,
+			LastLogin: time.Now().Unix(),
+			Token:     profile.AccessToken,
+		}
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存用户失败: %v", err)
+	}
+
+	return &user, nil
+}
+
+// GetUserByID 根据ID获取用户
+func GetUserByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := config.DB.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// uniqueUsername 为新用户生成不冲突的用户名
+func uniqueUsername(tx *gorm.DB, profile *OAuthProfile) (string, error) {
+	base := profile.Username
+	if base == "" {
+		base = profile.Provider + "_" + profile.ExternID
+	}
+
+	username := base
+	for i := 1; ; i++ {
+		var count int64
+		if err := tx.Model(&models.User{}).Unscoped().Where("username = ?", username).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return username, nil
+		}
+		username = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
+// ListUsers 获取所有用户
+func ListUsers() ([]models.User, error) {
+	var users []models.User
+	if err := config.DB.Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// SetUserRole 修改用户角色，不允许移除最后一个管理员
+func SetUserRole(id uint, role string) (*models.User, error) {
+	if role != models.RoleAdmin && role != models.RoleUser {
+		return nil, fmt.Errorf("无效的角色: %s", role)
+	}
+
+	var user models.User
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, id).Error; err != nil {
+			return err
+		}
+		if user.Role == role {
+			return nil
+		}
+
+		if user.Role == models.RoleAdmin {
+			var admins int64
+			if err := tx.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins).Error; err != nil {
+				return err
+			}
+			if admins <= 1 {
+				return ErrLastAdmin
+			}
+		}
+
+		return tx.Model(&user).Update("role", role).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// bootstrapRole 根据配置决定新用户的初始角色
+//
+// 提供商未确认邮箱已验证时不按管理员邮箱列表授权，避免任意设置邮箱的用户获得管理员权限。
+func bootstrapRole(tx *gorm.DB, profile *OAuthProfile) (string, error) {
+	if profile.EmailVerified && isAdminEmail(profile.Email) {
+		return models.RoleAdmin, nil
+	}
+
+	if config.FirstUserAdmin {
+		var count int64
+		if err := tx.Model(&models.User{}).Unscoped().Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return models.RoleAdmin, nil
+		}
+	}
+
+	return models.RoleUser, nil
+}
+
+// isAdminEmail 检查邮箱是否在管理员邮箱列表中
+func isAdminEmail(email string) bool {
+	if email == "" {
+		return false
+	}
+	for _, adminEmail := range config.AdminEmails {
+		if strings.EqualFold(adminEmail, email) {
+			return true
+		}
+	}
+	return false
+}
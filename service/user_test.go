@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+
+	"go-nextjs/config"
+	"go-nextjs/models"
+)
+
+func TestUpsertOAuthUserRoles(t *testing.T) {
+	setupTestDB(t)
+	config.AdminEmails = []string{"admin@example.com"}
+	config.FirstUserAdmin = false
+	t.Cleanup(func() { config.AdminEmails = nil })
+
+	login := func(id, email string, verified bool) *models.User {
+		t.Helper()
+		user, err := UpsertOAuthUser(&OAuthProfile{
+			Provider:      "oidc",
+			ExternID:      id,
+			Username:      id,
+			Email:         email,
+			EmailVerified: verified,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+
+	// 未验证的管理员邮箱不授予管理员
+	if u := login("1", "admin@example.com", false); u.Role != models.RoleUser {
+		t.Errorf("unverified admin email role = %s, want user", u.Role)
+	}
+	// 已有用户之后即使邮箱已验证也不会被提升
+	if u := login("1", "admin@example.com", true); u.Role != models.RoleUser {
+		t.Errorf("existing user role after login = %s, want user", u.Role)
+	}
+	if u, _ := GetUserByID(login("1", "admin@example.com", true).ID); u.Role != models.RoleUser {
+		t.Errorf("stored role = %s, want user", u.Role)
+	}
+	// 新用户的已验证管理员邮箱授予管理员
+	if u := login("2", "ADMIN@example.com", true); u.Role != models.RoleAdmin {
+		t.Errorf("verified admin email role = %s, want admin", u.Role)
+	}
+	if u := login("3", "other@example.com", true); u.Role != models.RoleUser {
+		t.Errorf("other email role = %s, want user", u.Role)
+	}
+}
+
+func TestUpsertOAuthUserFirstUserAdmin(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		setupTestDB(t)
+		config.FirstUserAdmin = enabled
+		first, err := UpsertOAuthUser(&OAuthProfile{Provider: "github", ExternID: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := UpsertOAuthUser(&OAuthProfile{Provider: "github", ExternID: "2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantFirst := models.RoleUser
+		if enabled {
+			wantFirst = models.RoleAdmin
+		}
+		if first.Role != wantFirst || second.Role != models.RoleUser {
+			t.Errorf("FirstUserAdmin=%v: roles = %s, %s", enabled, first.Role, second.Role)
+		}
+	}
+	config.FirstUserAdmin = false
+}
@@ -0,0 +1,66 @@
+"use client";
+
+import { Suspense, useEffect, useRef, useState } from "react";
+import Link from "next/link";
+import { useRouter, useSearchParams } from "next/navigation";
+import { AlertCircle } from "lucide-react";
+import { Button } from "@/components/ui/button";
+import { Loading } from "@/components/loading-page";
+import { exchangeLoginCode } from "@/lib/auth";
+
+// 只允许站内相对路径，防止开放重定向
+const safeRedirect = (path: string | null) =>
+  path && path.startsWith("/") && !path.startsWith("//") && !path.startsWith("/\\") ? path : "/";
+
+function Callback() {
+  const router = useRouter();
+  const searchParams = useSearchParams();
+  const [error, setError] = useState<string | null>(searchParams.get("error"));
+  // 登录码只能使用一次，避免开发模式下effect重复执行
+  const handled = useRef(false);
+
+  useEffect(() => {
+    if (handled.current || error) {
+      return;
+    }
+    handled.current = true;
+
+    const code = searchParams.get("code");
+    const redirect = safeRedirect(searchParams.get("redirect"));
+    // 先从地址栏和历史记录中移除登录码
+    window.history.replaceState(null, "", window.location.pathname);
+
+    if (!code) {
+      // Cookie模式下令牌已写入Cookie
+      router.replace(redirect);
+      return;
+    }
+    exchangeLoginCode(code)
+      .then(() => router.replace(redirect))
+      .catch((err: Error) => setError(err.message));
+  }, [error, router, searchParams]);
+
+  if (!error) {
+    return <Loading />;
+  }
+  return (
+    <div className="container flex flex-col items-center justify-center gap-6 py-24">
+      <div className="flex items-center justify-center rounded-full bg-muted p-6">
+        <AlertCircle className="h-12 w-12 text-muted-foreground" />
+      </div>
+      <h1 className="text-2xl font-bold">登录失败</h1>
+      <p className="text-muted-foreground">{error}</p>
+      <Link href="/">
+        <Button>返回首页</Button>
+      </Link>
+    </div>
+  );
+}
+
+export default function AuthCallbackPage() {
+  return (
+    <Suspense fallback={<Loading />}>
+      <Callback />
+    </Suspense>
+  );
+}
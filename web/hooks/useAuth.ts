@@ -1,6 +1,17 @@
 'use client';
 
 import { useState, useEffect, useCallback } from 'react';
+import {
+  type TokenPair,
+  REFRESH_AHEAD_MS,
+  authFetch,
+  clearTokens,
+  exchangeLoginCode,
+  getExpiresAt,
+  getToken,
+  refreshTokens,
+  saveTokens
+} from '@/lib/auth';
 
 interface User {
   id: string;
@@ -17,6 +28,9 @@ interface AuthState {
   error: string | null;
 }
 
+// 是否可能存在登录状态：本地保存了令牌，或Cookie模式下存在CSRF Cookie
+const hasSession = () => !!getToken() || document.cookie.includes('csrf_token=');
+
 export function useAuth() {
   const [auth, setAuth] = useState<AuthState>({
     isLoggedIn: false,
@@ -26,104 +40,115 @@ export function useAuth() {
     error: null
   });
 
-  // 初始化时从localStorage读取认证状态并验证token
+  // 获取当前用户信息，访问令牌过期时自动刷新
+  const fetchUser = useCallback(async () => {
+    const response = await authFetch('/api/auth/me');
+    if (!response.ok) {
+      throw new Error(response.status === 401 ? '会话已过期，请重新登录' : '获取用户信息失败');
+    }
+    const data = await response.json();
+    return data.user as User;
+  }, []);
+
+  // 初始化时验证登录状态
   useEffect(() => {
-    const fetchAuthStatus = async () => {
-      const token = localStorage.getItem('auth_token');
-      if (!token) {
-        setAuth(prev => ({ ...prev, isLoading: false }));
-        return;
-      }
-      
-      try {
-        // 验证token是否有效
-        const response = await fetch('/api/auth/me', {
-          headers: {
-            Authorization: `Bearer ${token}`
-          }
+    if (!hasSession()) {
+      setAuth(prev => ({ ...prev, isLoading: false }));
+      return;
+    }
+
+    fetchUser()
+      .then(user => {
+        setAuth({
+          isLoggedIn: true,
+          token: getToken(),
+          user,
+          isLoading: false,
+          error: null
         });
-        
-        if (response.ok) {
-          const data = await response.json();
-          setAuth({
-            isLoggedIn: true,
-            token: token,
-            user: data.user,
-            isLoading: false,
-            error: null
-          });
-        } else {
-          // Token无效，清除存储
-          localStorage.removeItem('auth_token');
-          setAuth({
-            isLoggedIn: false,
-            token: null,
-            user: null,
-            isLoading: false,
-            error: '会话已过期，请重新登录'
-          });
-        }
-      } catch (error) {
-        console.error('验证token失败', error);
-        localStorage.removeItem('auth_token');
+      })
+      .catch(error => {
+        console.error('验证登录状态失败', error);
+        clearTokens();
         setAuth({
           isLoggedIn: false,
           token: null,
           user: null,
           isLoading: false,
-          error: '验证登录状态时出错'
+          error: error instanceof Error ? error.message : '验证登录状态时出错'
         });
-      }
-    };
-    
-    fetchAuthStatus();
+      });
+  }, [fetchUser]);
+
+  // 刷新令牌，失败时退出登录状态
+  const refresh = useCallback(async () => {
+    const token = await refreshTokens();
+    if (token === null) {
+      setAuth({
+        isLoggedIn: false,
+        token: null,
+        user: null,
+        isLoading: false,
+        error: '会话已过期，请重新登录'
+      });
+      return false;
+    }
+    setAuth(prev => ({ ...prev, token: token || null }));
+    return true;
   }, []);
 
-  // 登录函数
-  const login = useCallback((token: string) => {
-    localStorage.setItem('auth_token', token);
+  // 访问令牌到期前主动刷新
+  useEffect(() => {
+    if (!auth.isLoggedIn || !auth.token) {
+      return;
+    }
+    const delay = Math.max(getExpiresAt() - Date.now() - REFRESH_AHEAD_MS, 0);
+    const timer = setTimeout(refresh, delay);
+    return () => clearTimeout(timer);
+  }, [auth.isLoggedIn, auth.token, refresh]);
+
+  // 登录函数，保存令牌后获取用户信息
+  const login = useCallback((tokens: TokenPair) => {
+    saveTokens(tokens);
     setAuth(prev => ({
       ...prev,
       isLoggedIn: true,
-      token,
+      token: tokens.token,
       isLoading: true,
       error: null
     }));
-    
-    // 登录后立即获取用户信息
-    fetch('/api/auth/me', {
-      headers: {
-        Authorization: `Bearer ${token}`
-      }
-    })
-    .then(res => {
-      if (res.ok) return res.json();
-      throw new Error('获取用户信息失败');
-    })
-    .then(data => {
-      setAuth(prev => ({
-        ...prev,
-        user: data.user,
-        isLoading: false
-      }));
-    })
-    .catch(err => {
-      console.error(err);
-      setAuth(prev => ({
-        ...prev,
-        isLoading: false,
-        error: '获取用户信息失败'
-      }));
-    });
-  }, []);
+
+    fetchUser()
+      .then(user => {
+        setAuth(prev => ({
+          ...prev,
+          token: getToken(),
+          user,
+          isLoading: false
+        }));
+      })
+      .catch(err => {
+        console.error(err);
+        setAuth(prev => ({
+          ...prev,
+          isLoading: false,
+          error: '获取用户信息失败'
+        }));
+      });
+  }, [fetchUser]);
+
+  // 使用回调中的一次性登录码登录
+  const loginWithCode = useCallback(async (code: string) => {
+    login(await exchangeLoginCode(code));
+  }, [login]);
 
   // 登出函数
   const logout = useCallback(() => {
-    // 调用后端登出接口（可选）
-    fetch('/api/auth/logout')
+    // 调用后端登出接口，吊销当前会话
+    authFetch('/api/auth/logout', { method: 'POST' })
       .catch(err => console.error('登出请求失败', err))
       .finally(() => {
-        localStorage.removeItem('auth_token');
+        clearTokens();
         setAuth({
           isLoggedIn: false,
           token: null,
@@ -134,7 +159,7 @@ export function useAuth() {
       });
   }, []);
 
-  // 获取认证头信息，用于API请求
+  // 获取认证头信息，用于API请求；需要自动刷新时使用 authFetch
   const getAuthHeader = useCallback(() => {
     if (auth.token) {
       return {
@@ -156,8 +181,11 @@ export function useAuth() {
     isLoading: auth.isLoading,
     error: auth.error,
     login,
+    loginWithCode,
     logout,
+    refresh,
+    authFetch,
     getAuthHeader,
     clearError
   };
-} 
\ No newline at end of file
+}
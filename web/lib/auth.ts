@@ -0,0 +1,130 @@
+import { BASE_URL } from "./api";
+
+// 登录或刷新后返回的令牌
+export interface TokenPair {
+  token: string;
+  refresh_token: string;
+  expires_in: number; // 访问令牌有效期（秒）
+}
+
+const TOKEN_KEY = "auth_token";
+const REFRESH_TOKEN_KEY = "refresh_token";
+const EXPIRES_AT_KEY = "auth_expires_at";
+const CSRF_COOKIE = "csrf_token";
+const CSRF_HEADER = "X-CSRF-Token";
+
+// 访问令牌到期前多久主动刷新（毫秒）
+export const REFRESH_AHEAD_MS = 60 * 1000;
+
+export const getToken = () => localStorage.getItem(TOKEN_KEY);
+
+export const getRefreshToken = () => localStorage.getItem(REFRESH_TOKEN_KEY);
+
+// 访问令牌的过期时间戳（毫秒），未知时为0
+export const getExpiresAt = () => Number(localStorage.getItem(EXPIRES_AT_KEY)) || 0;
+
+// 保存令牌，刷新令牌轮换后旧值随之失效
+export const saveTokens = (tokens: TokenPair) => {
+  localStorage.setItem(TOKEN_KEY, tokens.token);
+  localStorage.setItem(REFRESH_TOKEN_KEY, tokens.refresh_token);
+  localStorage.setItem(EXPIRES_AT_KEY, String(Date.now() + tokens.expires_in * 1000));
+};
+
+export const clearTokens = () => {
+  localStorage.removeItem(TOKEN_KEY);
+  localStorage.removeItem(REFRESH_TOKEN_KEY);
+  localStorage.removeItem(EXPIRES_AT_KEY);
+};
+
+// Cookie模式下读取CSRF令牌，修改类请求需要放入请求头
+const csrfHeader = (): Record<string, string> => {
+  const match = document.cookie.match(new RegExp(`(?:^|; )${CSRF_COOKIE}=([^;]*)`));
+  return match ? { [CSRF_HEADER]: decodeURIComponent(match[1]) } : {};
+};
+
+// 使用回调中的一次性登录码换取令牌
+export const exchangeLoginCode = async (code: string): Promise<TokenPair> => {
+  const response = await fetch(`${BASE_URL}/api/auth/exchange`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ code }),
+  });
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error(data.error || "登录失败");
+  }
+  saveTokens(data);
+  return data;
+};
+
+let refreshing: Promise<string | null> | null = null;
+
+// 使用刷新令牌换取新的访问令牌，并发调用共享同一个请求，避免轮换后的旧令牌被重复使用
+//
+// 返回新的访问令牌；Cookie模式下令牌在Cookie中，成功时返回空字符串；失败时清除令牌并返回null。
+export const refreshTokens = (): Promise<string | null> => {
+  if (!refreshing) {
+    refreshing = doRefresh().finally(() => {
+      refreshing = null;
+    });
+  }
+  return refreshing;
+};
+
+const doRefresh = async (): Promise<string | null> => {
+  const refreshToken = getRefreshToken();
+  try {
+    const response = await fetch(`${BASE_URL}/api/auth/refresh`, {
+      method: "POST",
+      credentials: "include",
+      headers: {
+        "Content-Type": "application/json",
+        ...(refreshToken ? {} : csrfHeader()),
+      },
+      body: JSON.stringify(refreshToken ? { refresh_token: refreshToken } : {}),
+    });
+    if (!response.ok) {
+      clearTokens();
+      return null;
+    }
+    const data = await response.json();
+    if (data.token) {
+      saveTokens(data);
+      return data.token;
+    }
+    return "";
+  } catch (error) {
+    console.error("刷新令牌失败", error);
+    return null;
+  }
+};
+
+// 携带认证信息发送请求，访问令牌失效时自动刷新并重试一次
+export const authFetch = async (url: string, options: RequestInit = {}): Promise<Response> => {
+  const send = (token: string | null) =>
+    fetch(`${BASE_URL}${url}`, {
+      ...options,
+      credentials: "include",
+      headers: {
+        ...(token ? { Authorization: `Bearer ${token}` } : csrfHeader()),
+        ...options.headers,
+      },
+    });
+
+  let token = getToken();
+  // 即将过期时先刷新，减少一次失败的请求
+  if (token && getExpiresAt() && getExpiresAt() - Date.now() < REFRESH_AHEAD_MS) {
+    token = (await refreshTokens()) ?? token;
+  }
+
+  const response = await send(token);
+  if (response.status !== 401) {
+    return response;
+  }
+
+  const refreshed = await refreshTokens();
+  if (refreshed === null) {
+    return response;
+  }
+  return send(refreshed || null);
+};